@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"reflect"
+	"time"
+)
+
+// Integer is the set of integer types supported by Increment and Decrement.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// SetIfAbsent sets key to value only if the key is missing or expired, it returns whether the value was set.
+func (c *TTLCache[K, V]) SetIfAbsent(key K, value V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	now := c.clock.Now()
+	if it, found := c.items[key]; found && !it.expired(now) {
+		c.mu.Unlock()
+		return false
+	}
+	evicted := c.insertLocked(c.newItem(key, value, now, ttl...), now)
+	c.mu.Unlock()
+
+	c.notifySet(key, value)
+	c.notify(evicted...)
+	return true
+}
+
+// CompareAndSwap sets key to new only if its current value equals old, it returns whether the value was swapped.
+// equal compares the values, nil means reflect.DeepEqual which also works for non-comparable types.
+// The item keeps its TTL.
+func (c *TTLCache[K, V]) CompareAndSwap(key K, old, new V, equal func(a, b V) bool) bool {
+	if equal == nil {
+		equal = func(a, b V) bool { return reflect.DeepEqual(a, b) }
+	}
+
+	// The lock is released with defer, so a panicking equal does not leave the cache locked
+	swapped, evicted := func() (bool, []eviction[K, V]) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		now := c.clock.Now()
+		it, found := c.items[key]
+		if !found || it.expired(now) || !equal(it.value, old) {
+			return false, nil
+		}
+		return true, c.insertLocked(it.withValue(new), now)
+	}()
+	if !swapped {
+		return false
+	}
+
+	c.notifySet(key, new)
+	c.notify(evicted...)
+	return true
+}
+
+// Update atomically replaces the value of key with the result of fn, which gets the current value and whether it exists.
+// If fn returns false the key is removed instead. An existing item keeps its tags, and its TTL unless ttl is given,
+// a new item gets ttl. Update returns the new value and whether the key exists afterwards.
+// fn runs with the lock held, so it must not use the cache.
+func (c *TTLCache[K, V]) Update(key K, fn func(old V, ok bool) (V, bool), ttl ...time.Duration) (V, bool) {
+	return c.update(key, fn, false, ttl...)
+}
+
+// update implements Update, with ttlOnCreate the ttl is only applied to new items.
+func (c *TTLCache[K, V]) update(key K, fn func(old V, ok bool) (V, bool), ttlOnCreate bool, ttl ...time.Duration) (V, bool) {
+	value, keep, evicted := c.updateLocked(key, fn, ttlOnCreate, ttl...)
+	if keep {
+		c.notifySet(key, value)
+	}
+	c.notify(evicted...)
+	if !keep {
+		var zeroV V
+		return zeroV, false
+	}
+	return value, true
+}
+
+// updateLocked runs the locked part of update. The lock is released with defer, so a panicking fn does not
+// leave the cache locked.
+func (c *TTLCache[K, V]) updateLocked(key K, fn func(old V, ok bool) (V, bool), ttlOnCreate bool, ttl ...time.Duration) (V, bool, []eviction[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	it, found := c.items[key]
+	alive := found && !it.expired(now)
+
+	var oldV V
+	if alive {
+		oldV = it.value
+	}
+	value, keep := fn(oldV, alive)
+
+	var evicted []eviction[K, V]
+	switch {
+	case keep && alive && (len(ttl) == 0 || ttlOnCreate):
+		evicted = c.insertLocked(it.withValue(value), now)
+	case keep:
+		replacement := c.newItem(key, value, now, ttl...)
+		if alive {
+			// A new TTL does not make it another item, it stays under its tags
+			replacement.tags = it.tags
+		}
+		evicted = c.insertLocked(replacement, now)
+	case found:
+		c.deleteLocked(it)
+		removed := it.replaced(now)
+		if alive {
+			removed.reason = ReasonRemoved
+		}
+		evicted = append(evicted, removed)
+	}
+	return value, keep, evicted
+}
+
+// Increment atomically adds delta to the integer stored under key and returns the new value.
+// A missing or expired key counts as zero and is created with the optional ttl, an existing key keeps its TTL.
+func Increment[K comparable, V Integer](c *TTLCache[K, V], key K, delta V, ttl ...time.Duration) V {
+	value, _ := c.update(key, func(old V, ok bool) (V, bool) {
+		return old + delta, true
+	}, true, ttl...)
+	return value
+}
+
+// Decrement atomically subtracts delta from the integer stored under key and returns the new value, see Increment.
+func Decrement[K comparable, V Integer](c *TTLCache[K, V], key K, delta V, ttl ...time.Duration) V {
+	value, _ := c.update(key, func(old V, ok bool) (V, bool) {
+		return old - delta, true
+	}, true, ttl...)
+	return value
+}
+
+// withValue returns a copy of the item holding value, with the same key, expiry and tags.
+func (i *item[K, V]) withValue(value V) *item[K, V] {
+	return &item[K, V]{key: i.key, value: value, ttl: i.ttl, baseTTL: i.baseTTL, expiry: i.expiry, index: -1, tags: i.tags}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetIfAbsent(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, string]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	if !c.SetIfAbsent("lease", "owner-1", time.Second) {
+		t.Fatal("Expected first SetIfAbsent to win")
+	}
+	if c.SetIfAbsent("lease", "owner-2", time.Second) {
+		t.Fatal("Expected second SetIfAbsent to lose")
+	}
+	clock.Advance(2 * time.Second)
+	if !c.SetIfAbsent("lease", "owner-2", time.Second) {
+		t.Fatal("Expected SetIfAbsent to win over an expired item")
+	}
+	if value, _ := c.Get("lease"); value != "owner-2" {
+		t.Errorf("Expected owner-2, got %v", value)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, []string]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.Set("list", []string{"a"}, time.Minute)
+	if c.CompareAndSwap("list", []string{"b"}, []string{"c"}, nil) {
+		t.Error("Expected CompareAndSwap with a stale value to fail")
+	}
+	if !c.CompareAndSwap("list", []string{"a"}, []string{"a", "b"}, nil) {
+		t.Fatal("Expected CompareAndSwap with the current value to succeed")
+	}
+	sameLen := func(a, b []string) bool { return len(a) == len(b) }
+	if !c.CompareAndSwap("list", []string{"x", "y"}, []string{"z"}, sameLen) {
+		t.Fatal("Expected the custom equality func to be used")
+	}
+	if ttl, _ := c.TTL("list"); ttl != time.Minute {
+		t.Errorf("Expected CompareAndSwap to keep the TTL, got %v", ttl)
+	}
+	if c.CompareAndSwap("missing", nil, []string{"a"}, nil) {
+		t.Error("Expected CompareAndSwap on a missing key to fail")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	value, ok := c.Update("key", func(old int, ok bool) (int, bool) {
+		if ok {
+			t.Error("Expected a missing key")
+		}
+		return 10, true
+	}, time.Minute)
+	if !ok || value != 10 {
+		t.Fatalf("Expected 10, got %v", value)
+	}
+
+	value, _ = c.Update("key", func(old int, ok bool) (int, bool) {
+		return old * 2, true
+	})
+	if value != 20 {
+		t.Errorf("Expected 20, got %v", value)
+	}
+	if ttl, _ := c.TTL("key"); ttl <= 0 {
+		t.Errorf("Expected Update to keep the TTL, got %v", ttl)
+	}
+
+	if _, ok = c.Update("key", func(old int, ok bool) (int, bool) { return 0, false }); ok {
+		t.Error("Expected Update returning false to remove the key")
+	}
+	if _, found := c.Get("key"); found {
+		t.Error("Expected key to be removed")
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int64]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Increment(c, "counter", 1, time.Minute)
+		}()
+	}
+	wg.Wait()
+	if value, _ := c.Get("counter"); value != 100 {
+		t.Errorf("Expected 100, got %v", value)
+	}
+
+	// The TTL is only applied when the counter is created
+	clock.Advance(30 * time.Second)
+	Increment(c, "counter", 1, time.Minute)
+	if ttl, _ := c.TTL("counter"); ttl != 30*time.Second {
+		t.Errorf("Expected the window to keep its TTL, got %v", ttl)
+	}
+	if value := Decrement(c, "counter", 11); value != 90 {
+		t.Errorf("Expected 90, got %v", value)
+	}
+}
+
+func TestCallbackPanicReleasesLock(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+	c.Set("key", 1)
+
+	recovered := func(fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the callback panic to propagate")
+			}
+		}()
+		fn()
+	}
+	recovered(func() {
+		c.Update("key", func(old int, ok bool) (int, bool) { panic("boom") })
+	})
+	recovered(func() {
+		c.CompareAndSwap("key", 1, 2, func(a, b int) bool { panic("boom") })
+	})
+
+	done := make(chan struct{})
+	go func() {
+		c.Set("key", 3)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the cache to stay usable after a panicking callback")
+	}
+	if value, _ := c.Get("key"); value != 3 {
+		t.Errorf("Expected 3, got %v", value)
+	}
+}
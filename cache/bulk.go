@@ -0,0 +1,132 @@
+package cache
+
+import "time"
+
+// Len returns the number of unexpired items in the cache.
+func (c *TTLCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	n := 0
+	for _, it := range c.items {
+		if !it.expired(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Keys returns the keys of all unexpired items, in no particular order.
+func (c *TTLCache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	keys := make([]K, 0, len(c.items))
+	for key, it := range c.items {
+		if !it.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for every unexpired item until fn returns false. It iterates over a snapshot taken
+// when Range is called, so fn may safely use the cache and does not see changes made during the iteration.
+func (c *TTLCache[K, V]) Range(fn func(key K, value V) bool) {
+	for _, it := range c.snapshot() {
+		if !fn(it.key, it.value) {
+			return
+		}
+	}
+}
+
+// Clear removes all items from the cache.
+func (c *TTLCache[K, V]) Clear() {
+	c.mu.Lock()
+	now := c.clock.Now()
+	evicted := make([]eviction[K, V], 0, len(c.items))
+	for key, it := range c.items {
+		reason := ReasonRemoved
+		if it.expired(now) {
+			reason = ReasonExpired
+		}
+		evicted = append(evicted, eviction[K, V]{key: key, value: it.value, reason: reason})
+	}
+	c.items = make(map[K]*item[K, V])
+	c.expiries = nil
+	c.tags = nil
+	c.negative = nil
+	if c.policy != nil {
+		c.policy = c.newPolicy()
+	}
+	c.mu.Unlock()
+
+	c.notify(evicted...)
+}
+
+// SetMany adds or updates all key-value pairs of items with the same optional TTL under a single lock.
+func (c *TTLCache[K, V]) SetMany(items map[K]V, ttl ...time.Duration) {
+	c.mu.Lock()
+	now := c.clock.Now()
+	var evicted []eviction[K, V]
+	for key, value := range items {
+		evicted = append(evicted, c.insertLocked(c.newItem(key, value, now, ttl...), now)...)
+	}
+	c.mu.Unlock()
+
+	for key, value := range items {
+		c.notifySet(key, value)
+	}
+	c.notify(evicted...)
+}
+
+// GetMany returns the values of all given keys that are present and unexpired.
+func (c *TTLCache[K, V]) GetMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, found := c.Get(key); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// RemoveMany deletes all given keys under a single lock.
+func (c *TTLCache[K, V]) RemoveMany(keys ...K) {
+	c.mu.Lock()
+	now := c.clock.Now()
+	var evicted []eviction[K, V]
+	for _, key := range keys {
+		delete(c.negative, key)
+		it, found := c.items[key]
+		if !found {
+			continue
+		}
+		c.deleteLocked(it)
+		reason := ReasonRemoved
+		if it.expired(now) {
+			reason = ReasonExpired
+		}
+		evicted = append(evicted, eviction[K, V]{key: key, value: it.value, reason: reason})
+	}
+	c.mu.Unlock()
+
+	c.notify(evicted...)
+}
+
+// snapshot returns a copy of all unexpired items, so callers can work on them without holding the lock.
+func (c *TTLCache[K, V]) snapshot() []item[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	items := make([]item[K, V], 0, len(c.items))
+	for _, it := range c.items {
+		if !it.expired(now) {
+			items = append(items, *it)
+		}
+	}
+	return items
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLenKeysAndRange(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.SetMany(map[string]int{"a": 1, "b": 2, "c": 3})
+	c.Set("expired", 4, time.Second)
+	clock.Advance(2 * time.Second)
+
+	if n := c.Len(); n != 3 {
+		t.Errorf("Expected 3 items, got %d", n)
+	}
+	keys := c.Keys()
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("Expected keys [a b c], got %v", keys)
+	}
+
+	sum := 0
+	c.Range(func(key string, value int) bool {
+		// The lock is not held, so the callback may write to the cache
+		c.Set(key+"-copy", value)
+		sum += value
+		return true
+	})
+	if sum != 6 {
+		t.Errorf("Expected Range to visit every unexpired item, sum %d", sum)
+	}
+	if n := c.Len(); n != 6 {
+		t.Errorf("Expected 6 items after copying, got %d", n)
+	}
+
+	visited := 0
+	c.Range(func(key string, value int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Expected Range to stop when fn returns false, visited %d", visited)
+	}
+}
+
+func TestGetManyAndRemoveMany(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	c.SetMany(map[string]int{"a": 1, "b": 2, "c": 3}, time.Minute)
+	values := c.GetMany("a", "b", "missing")
+	if len(values) != 2 || values["a"] != 1 || values["b"] != 2 {
+		t.Errorf("Expected a and b, got %v", values)
+	}
+
+	c.RemoveMany("a", "c", "missing")
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Expected only b to be left, got %v", keys)
+	}
+	if stats := c.Stats(); stats.Sets != 3 || stats.Removals != 2 {
+		t.Errorf("Expected 3 sets and 2 removals, got %+v", stats)
+	}
+}
+
+func TestClear(t *testing.T) {
+	var removed int
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			removed++
+		},
+	})
+	defer c.Close()
+
+	c.SetMany(map[string]int{"a": 1, "b": 2}, time.Minute)
+	c.Set("c", 3)
+	c.Clear()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Expected empty cache, got %d items", n)
+	}
+	if removed != 3 {
+		t.Errorf("Expected OnEvict for every cleared item, got %d", removed)
+	}
+
+	// The cache is still usable after Clear
+	c.Set("d", 4, time.Minute)
+	if value, found := c.Get("d"); !found || value != 4 {
+		t.Errorf("Expected 4, got %v", value)
+	}
+}
@@ -0,0 +1,366 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ByteCacheOptions configures a ByteCache.
+// Shards is the number of shards, rounded up to a power of two, zero means four shards per CPU.
+// MaxBytes is the memory allocated up front for entries and split between the shards, zero means 64 MiB.
+// Every entry takes its key, its value and a 22 byte header, the oldest entries are evicted to make room.
+// CleanInterval is how often the janitor purges expired entries, zero means the default interval.
+// DisableJanitor skips the background janitor, expired entries are then only purged by DeleteExpired.
+// Clock is the time source for TTLs, nil means the system clock.
+type ByteCacheOptions struct {
+	Shards         int
+	MaxBytes       int
+	CleanInterval  time.Duration
+	DisableJanitor bool
+	Clock          Clock
+}
+
+// ByteCache is a cache of []byte values by string key built for millions of entries.
+// Entries are serialized into large pre-allocated ring buffers indexed by maps of integers,
+// so the garbage collector has no per-entry pointers to scan. Values are copied in and out.
+// When a shard is full its oldest entries are evicted first, whether they are read or not.
+type ByteCache struct {
+	shards        []*byteShard
+	seed          maphash.Seed
+	mask          uint64
+	clock         Clock
+	stats         counters
+	cleanInterval time.Duration
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	closeOnce     sync.Once
+}
+
+// byteShard stores its entries back to back in ring, from the oldest at head to the newest before tail.
+// When an entry does not fit before the end of ring it is written at the start and end marks where the data wraps.
+// index maps key hashes to entry offsets, a removed or replaced entry stays in ring until it is evicted.
+type byteShard struct {
+	mu      sync.RWMutex
+	index   map[uint64]uint32
+	ring    []byte
+	head    int
+	tail    int
+	end     int
+	wrapped bool
+	entries int // entries in ring, including the ones no longer indexed
+}
+
+// byteHeaderSize is the size of an entry header: expiry in unix nanoseconds (zero means none), key hash,
+// key length and value length.
+const byteHeaderSize = 8 + 8 + 2 + 4
+
+var defaultMaxBytes = 64 << 20
+
+var _ ICache[string, []byte] = (*ByteCache)(nil)
+
+// NewByteCache creates a ByteCache, unless the janitor is disabled it must be closed to release its background goroutine.
+func NewByteCache(opts ByteCacheOptions) *ByteCache {
+	shardCount := opts.Shards
+	if shardCount <= 0 {
+		shardCount = 4 * runtime.GOMAXPROCS(0)
+	}
+	n := 1
+	for n < shardCount {
+		n <<= 1
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	shardBytes := int64(maxBytes / n)
+	if shardBytes > math.MaxUint32 {
+		// Offsets are stored as uint32
+		shardBytes = math.MaxUint32
+	}
+
+	c := &ByteCache{
+		shards:        make([]*byteShard, n),
+		seed:          maphash.MakeSeed(),
+		mask:          uint64(n - 1),
+		clock:         opts.Clock,
+		cleanInterval: opts.CleanInterval,
+		stopCh:        make(chan struct{}),
+	}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+	if c.cleanInterval <= 0 {
+		c.cleanInterval = defaultCleanInterval
+	}
+	for i := range c.shards {
+		c.shards[i] = &byteShard{index: make(map[uint64]uint32), ring: make([]byte, int(shardBytes))}
+	}
+	if !opts.DisableJanitor {
+		c.doneCh = make(chan struct{})
+		go c.runJanitor()
+	}
+	return c
+}
+
+// Set adds or updates a key-value pair in the cache with optional TTL, if no TTL is specified the item will not expire.
+// An entry larger than a shard or with a key longer than 65535 bytes is not stored, and the previous value
+// of the key is removed, since the caller has overwritten it.
+func (c *ByteCache) Set(key string, value []byte, ttl ...time.Duration) {
+	c.stats.sets.Add(1)
+	size := byteHeaderSize + len(key) + len(value)
+	h := maphash.String(c.seed, key)
+	s := c.shards[h&c.mask]
+	if len(key) > math.MaxUint16 || size > len(s.ring) {
+		s.mu.Lock()
+		offset, replaced := s.index[h]
+		if replaced {
+			// The index entry may belong to another key with the same hash
+			if _, _, replaced = s.read(offset, key); replaced {
+				delete(s.index, h)
+			}
+		}
+		s.mu.Unlock()
+		if replaced {
+			c.stats.evicted(ReasonReplaced)
+		}
+		c.stats.evicted(ReasonEvicted)
+		return
+	}
+	var expiry int64
+	if len(ttl) > 0 {
+		expiry = c.clock.Now().Add(ttl[0]).UnixNano()
+	}
+
+	s.mu.Lock()
+	evicted := 0
+	offset, ok := s.alloc(size)
+	for !ok {
+		if s.evictOldest() {
+			evicted++
+		}
+		offset, ok = s.alloc(size)
+	}
+	entry := s.ring[offset : offset+size]
+	binary.LittleEndian.PutUint64(entry[0:], uint64(expiry))
+	binary.LittleEndian.PutUint64(entry[8:], h)
+	binary.LittleEndian.PutUint16(entry[16:], uint16(len(key)))
+	binary.LittleEndian.PutUint32(entry[18:], uint32(len(value)))
+	copy(entry[byteHeaderSize:], key)
+	copy(entry[byteHeaderSize+len(key):], value)
+	_, replaced := s.index[h]
+	s.index[h] = uint32(offset)
+	s.entries++
+	s.mu.Unlock()
+
+	for i := 0; i < evicted; i++ {
+		c.stats.evicted(ReasonEvicted)
+	}
+	if replaced {
+		c.stats.evicted(ReasonReplaced)
+	}
+}
+
+// Get retrieves a copy of the value associated with the given key.
+func (c *ByteCache) Get(key string) ([]byte, bool) {
+	h := maphash.String(c.seed, key)
+	s := c.shards[h&c.mask]
+	now := c.clock.Now().UnixNano()
+
+	s.mu.RLock()
+	var value []byte
+	offset, found := s.index[h]
+	if found {
+		var expiry int64
+		value, expiry, found = s.read(offset, key)
+		found = found && !byteExpired(expiry, now)
+	}
+	if found {
+		value = append([]byte(nil), value...)
+	}
+	s.mu.RUnlock()
+
+	if !found {
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+	c.stats.hits.Add(1)
+	return value, true
+}
+
+// Remove deletes the key-value pair with the specified key.
+func (c *ByteCache) Remove(key string) {
+	c.Pop(key)
+}
+
+// Pop removes and returns the value associated with the specified key.
+func (c *ByteCache) Pop(key string) ([]byte, bool) {
+	h := maphash.String(c.seed, key)
+	s := c.shards[h&c.mask]
+	now := c.clock.Now().UnixNano()
+
+	s.mu.Lock()
+	var value []byte
+	var expiry int64
+	offset, found := s.index[h]
+	if found {
+		value, expiry, found = s.read(offset, key)
+	}
+	if found {
+		value = append([]byte(nil), value...)
+		delete(s.index, h)
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !found:
+		return nil, false
+	case byteExpired(expiry, now):
+		c.stats.evicted(ReasonExpired)
+		return nil, false
+	default:
+		c.stats.evicted(ReasonRemoved)
+		return value, true
+	}
+}
+
+// Len returns the number of entries held, including expired ones not yet purged.
+func (c *ByteCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.index)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// DeleteExpired removes all expired entries from the index, it is what the janitor runs on every tick.
+// Their space is reclaimed when the ring buffer wraps around to them.
+func (c *ByteCache) DeleteExpired() {
+	now := c.clock.Now().UnixNano()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		expired := 0
+		for h, offset := range s.index {
+			if byteExpired(int64(binary.LittleEndian.Uint64(s.ring[offset:])), now) {
+				delete(s.index, h)
+				expired++
+			}
+		}
+		s.mu.Unlock()
+
+		for i := 0; i < expired; i++ {
+			c.stats.evicted(ReasonExpired)
+		}
+	}
+}
+
+// Stats returns a snapshot of the cache statistics, Cost is the number of ring buffer bytes in use,
+// including the space of entries removed but not yet overwritten.
+func (c *ByteCache) Stats() Stats {
+	stats := c.stats.snapshot()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		stats.Size += len(s.index)
+		stats.Cost += int64(s.used())
+		s.mu.RUnlock()
+	}
+	return stats
+}
+
+// Close stops the janitor. It is idempotent and always returns nil.
+func (c *ByteCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stopCh)
+		if c.doneCh != nil {
+			<-c.doneCh
+		}
+	})
+	return nil
+}
+
+func (c *ByteCache) runJanitor() {
+	defer close(c.doneCh)
+	ticker := time.NewTicker(c.cleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func byteExpired(expiry, now int64) bool {
+	return expiry != 0 && expiry < now
+}
+
+// read returns the value and expiry of the entry at offset if it belongs to key, hashes may collide.
+func (s *byteShard) read(offset uint32, key string) ([]byte, int64, bool) {
+	entry := s.ring[offset:]
+	keyLen := int(binary.LittleEndian.Uint16(entry[16:]))
+	valueLen := int(binary.LittleEndian.Uint32(entry[18:]))
+	if string(entry[byteHeaderSize:byteHeaderSize+keyLen]) != key {
+		return nil, 0, false
+	}
+	start := byteHeaderSize + keyLen
+	return entry[start : start+valueLen], int64(binary.LittleEndian.Uint64(entry)), true
+}
+
+// alloc reserves size bytes at tail and returns their offset, or false if the oldest entries must be evicted first.
+func (s *byteShard) alloc(size int) (int, bool) {
+	if s.entries == 0 {
+		s.head, s.tail, s.wrapped = 0, 0, false
+	}
+	if !s.wrapped {
+		if s.tail+size <= len(s.ring) {
+			s.tail += size
+			return s.tail - size, true
+		}
+		if size > s.head {
+			return 0, false
+		}
+		s.end, s.tail, s.wrapped = s.tail, size, true
+		return 0, true
+	}
+	if s.tail+size > s.head {
+		return 0, false
+	}
+	s.tail += size
+	return s.tail - size, true
+}
+
+// evictOldest drops the entry at head and reports whether it was still indexed.
+func (s *byteShard) evictOldest() bool {
+	entry := s.ring[s.head:]
+	h := binary.LittleEndian.Uint64(entry[8:])
+	size := byteHeaderSize + int(binary.LittleEndian.Uint16(entry[16:])) + int(binary.LittleEndian.Uint32(entry[18:]))
+	indexed := false
+	if offset, found := s.index[h]; found && int(offset) == s.head {
+		delete(s.index, h)
+		indexed = true
+	}
+	s.head += size
+	s.entries--
+	if s.wrapped && s.head == s.end {
+		s.head, s.wrapped = 0, false
+	}
+	return indexed
+}
+
+// used returns the number of ring bytes between head and tail.
+func (s *byteShard) used() int {
+	if s.entries == 0 {
+		return 0
+	}
+	if s.wrapped {
+		return s.end - s.head + s.tail
+	}
+	return s.tail - s.head
+}
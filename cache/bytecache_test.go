@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"bytes"
+	"hash/maphash"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestByteCache(t *testing.T) {
+	c := NewByteCache(ByteCacheOptions{Shards: 2, MaxBytes: 1 << 16, DisableJanitor: true})
+	defer c.Close()
+
+	value := []byte("value")
+	c.Set("key", value)
+	value[0] = 'X'
+	got, found := c.Get("key")
+	if !found || string(got) != "value" {
+		t.Fatalf("Expected a copy of value, got %q", got)
+	}
+	got[0] = 'X'
+	if got, _ := c.Get("key"); string(got) != "value" {
+		t.Errorf("Expected Get to return a copy, got %q", got)
+	}
+
+	c.Set("key", []byte("updated"))
+	if got, _ := c.Get("key"); string(got) != "updated" {
+		t.Errorf("Expected updated, got %q", got)
+	}
+	if got, found := c.Pop("key"); !found || string(got) != "updated" {
+		t.Errorf("Expected to pop updated, got %q", got)
+	}
+	if _, found := c.Get("key"); found {
+		t.Error("Expected Pop to remove the key")
+	}
+	c.Set("empty", nil)
+	if got, found := c.Get("empty"); !found || len(got) != 0 {
+		t.Errorf("Expected an empty value, got %q", got)
+	}
+	c.Remove("empty")
+	if n := c.Len(); n != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", n)
+	}
+}
+
+func TestByteCacheTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := NewByteCache(ByteCacheOptions{Shards: 1, MaxBytes: 1 << 16, DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.Set("short", []byte("a"), time.Second)
+	c.Set("long", []byte("b"), time.Hour)
+	c.Set("forever", []byte("c"))
+	clock.Advance(time.Minute)
+	if _, found := c.Get("short"); found {
+		t.Error("Expected short to be expired")
+	}
+	if _, found := c.Get("long"); !found {
+		t.Error("Expected long to be kept")
+	}
+	c.DeleteExpired()
+	if stats := c.Stats(); stats.Size != 2 || stats.Expirations != 1 {
+		t.Errorf("Expected 2 entries and 1 expiration, got %+v", stats)
+	}
+}
+
+func TestByteCacheEvictsOldest(t *testing.T) {
+	entry := byteHeaderSize + 2 + 8
+	c := NewByteCache(ByteCacheOptions{Shards: 1, MaxBytes: 10 * entry, DisableJanitor: true})
+	defer c.Close()
+
+	for i := 10; i < 25; i++ {
+		c.Set(strconv.Itoa(i), bytes.Repeat([]byte{byte(i)}, 8))
+	}
+	for i := 10; i < 25; i++ {
+		got, found := c.Get(strconv.Itoa(i))
+		if i < 15 && found {
+			t.Errorf("Expected %d to be evicted", i)
+		}
+		if i >= 15 && (!found || got[0] != byte(i)) {
+			t.Errorf("Expected %d to be kept, got %v", i, got)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 5 || stats.Cost != int64(10*entry) {
+		t.Errorf("Expected 5 evictions of a full ring, got %+v", stats)
+	}
+
+	c.Set("huge", make([]byte, 10*entry))
+	if _, found := c.Get("huge"); found {
+		t.Error("Expected an entry larger than the shard not to be stored")
+	}
+}
+
+// TestByteCacheRing replays random writes of random sizes against a map, so entries wrap around the ring.
+func TestByteCacheRing(t *testing.T) {
+	c := NewByteCache(ByteCacheOptions{Shards: 1, MaxBytes: 4096, DisableJanitor: true})
+	defer c.Close()
+	r := rand.New(rand.NewSource(1))
+	want := make(map[string][]byte)
+
+	for i := 0; i < 20000; i++ {
+		key := strconv.Itoa(r.Intn(200))
+		if r.Intn(10) == 0 {
+			c.Remove(key)
+			delete(want, key)
+			continue
+		}
+		value := make([]byte, r.Intn(300))
+		r.Read(value)
+		c.Set(key, value)
+		want[key] = value
+	}
+	for key, value := range want {
+		// Evicted keys are gone, kept keys must hold their latest value
+		if got, found := c.Get(key); found && !bytes.Equal(got, value) {
+			t.Fatalf("Key %s: expected its latest value, got a different one", key)
+		}
+	}
+	if c.Len() == 0 {
+		t.Error("Expected the latest entries to be kept")
+	}
+}
+
+func TestByteCacheHashCollision(t *testing.T) {
+	c := NewByteCache(ByteCacheOptions{Shards: 1, MaxBytes: 1 << 10, DisableJanitor: true})
+	defer c.Close()
+
+	c.Set("a", []byte("1"))
+	s := c.shards[0]
+	// Point the hash of b at the entry of a, as a collision would
+	s.index[maphash.String(c.seed, "b")] = s.index[maphash.String(c.seed, "a")]
+	if _, found := c.Get("b"); found {
+		t.Error("Expected the stored key to be checked on a hash collision")
+	}
+	if _, found := c.Pop("b"); found {
+		t.Error("Expected Pop to check the stored key")
+	}
+	if got, _ := c.Get("a"); string(got) != "1" {
+		t.Errorf("Expected a to be kept, got %q", got)
+	}
+}
+
+func BenchmarkByteCacheSet(b *testing.B) {
+	c := NewByteCache(ByteCacheOptions{MaxBytes: 256 << 20, DisableJanitor: true})
+	defer c.Close()
+	value := make([]byte, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(strconv.Itoa(i), value)
+	}
+}
+
+func BenchmarkByteCacheGet(b *testing.B) {
+	c := NewByteCache(ByteCacheOptions{MaxBytes: 256 << 20, DisableJanitor: true})
+	defer c.Close()
+	for i := 0; i < 100000; i++ {
+		c.Set(strconv.Itoa(i), make([]byte, 100))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(strconv.Itoa(i % 100000))
+			i++
+		}
+	})
+}
+
+func TestByteCacheRejectedSetRemovesOldValue(t *testing.T) {
+	c := NewByteCache(ByteCacheOptions{Shards: 1, MaxBytes: 1 << 10, DisableJanitor: true})
+	defer c.Close()
+
+	c.Set("k", []byte("old"))
+	c.Set("other", []byte("kept"))
+	c.Set("k", make([]byte, 4<<10))
+	if got, found := c.Get("k"); found {
+		t.Errorf("Expected the overwritten value to be gone, got %q", got)
+	}
+	if got, _ := c.Get("other"); string(got) != "kept" {
+		t.Errorf("Expected other keys to be kept, got %q", got)
+	}
+}
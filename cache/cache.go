@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"container/heap"
+	"context"
+	"github.com/huahuayu/kit/logger"
 	"sync"
 	"time"
 )
@@ -11,36 +14,218 @@ type ICache[K comparable, V any] interface {
 	Get(key K) (V, bool)
 	Remove(key K)
 	Pop(key K) (V, bool)
+	Close() error
+}
+
+// EvictReason describes why an item left the cache.
+type EvictReason int
+
+const (
+	// ReasonExpired means the item outlived its TTL.
+	ReasonExpired EvictReason = iota
+	// ReasonRemoved means the item was deleted by Remove or Pop.
+	ReasonRemoved
+	// ReasonEvicted means the item was dropped to keep the cache within its capacity.
+	ReasonEvicted
+	// ReasonReplaced means the item was overwritten by Set.
+	ReasonReplaced
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// Options configures a TTLCache created by NewWithOptions.
+// CleanInterval is how often the janitor purges expired items, zero means the default interval.
+// DisableJanitor skips the background janitor, expired items are then only purged by DeleteExpired.
+// Context stops the janitor and background refreshes when it is done, the same as calling Close.
+// OnEvict is called outside the lock whenever an item leaves the cache.
+// OnStats is called with a Stats snapshot every StatsInterval (default one minute), it is the hook to export
+// the cache statistics to a metrics system.
+// Clock is the time source for TTLs, nil means the system clock.
+// KeyCodec and ValueCodec encode snapshots, nil means GobCodec.
+// SnapshotPath warm-starts the cache from the snapshot at that path and saves a snapshot there on Close,
+// and every SnapshotInterval if it is set.
+// SlidingExpiration extends the TTL of an item on every Get, so only idle items expire.
+// TTLJitter adds a random duration in [0, TTLJitter) to every TTL, so keys set together do not expire together.
+// Loader is used by background refreshes and by GetOrLoad calls without a loader of their own.
+// RefreshAhead reloads an item in the background when it is read within that window before its expiry.
+// StaleGrace keeps serving an expired item for that long after its expiry while it is reloaded in the background.
+// Both need a Loader.
+// MaxCost bounds the total cost of the items, the least recently used items are evicted to stay under it,
+// zero means unbounded. Cost returns the cost of a value, typically its size in bytes, nil means the length
+// of []byte and string values and one for anything else. Negative costs count as zero.
+// Policy chooses what is evicted to stay under MaxCost, the default is PolicyLRU.
+type Options[K comparable, V any] struct {
+	CleanInterval     time.Duration
+	DisableJanitor    bool
+	Context           context.Context
+	OnEvict           func(key K, value V, reason EvictReason)
+	OnStats           func(stats Stats)
+	StatsInterval     time.Duration
+	Clock             Clock
+	KeyCodec          Codec[K]
+	ValueCodec        Codec[V]
+	SnapshotPath      string
+	SnapshotInterval  time.Duration
+	SlidingExpiration bool
+	TTLJitter         time.Duration
+	Loader            Loader[K, V]
+	RefreshAhead      time.Duration
+	StaleGrace        time.Duration
+	MaxCost           int64
+	Cost              func(value V) int64
+	Policy            EvictionPolicy
 }
 
 // TTLCache is a generic in-memory key-value cache with optional TTL support.
 type TTLCache[K comparable, V any] struct {
-	items         map[K]*item[V]
+	items         map[K]*item[K, V]
+	expiries      expiryHeap[K, V]
+	tags          map[string]map[K]struct{}
+	negative      map[K]negativeItem
 	mu            sync.RWMutex
-	cleanInterval *time.Duration
+	clock         Clock
+	loads         group[K, V]
+	stats         counters
+	watchers      watchHub[K, V]
+	cleanInterval time.Duration
+	janitor       bool
+	onEvict       func(key K, value V, reason EvictReason)
+	onStats       func(stats Stats)
+	statsInterval time.Duration
+	keyCodec      Codec[K]
+	valueCodec    Codec[V]
+	snapshotPath  string
+	snapshotEvery time.Duration
+	sliding       bool
+	jitter        time.Duration
+	loader        Loader[K, V]
+	refreshAhead  time.Duration
+	staleGrace    time.Duration
+	maxCost       int64
+	evictPolicy   EvictionPolicy
+	policy        policy[K]
+	cost          func(value V) int64
+	ctx           context.Context
+	cancel        context.CancelFunc
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	closeOnce     sync.Once
+	closeErr      error
+}
+
+// item is a cached value, ttl is the TTL it was given and index is its position in the expiry heap
+// or -1 if it does not expire. tags are the tags it is indexed under.
+type item[K comparable, V any] struct {
+	key     K
+	value   V
+	ttl     time.Duration // with jitter, reused by sliding expiration and Touch
+	baseTTL time.Duration // without jitter, reused by background refreshes so jitter does not add up
+	expiry  time.Time
+	index   int
+	tags    []string
 }
 
-type item[V any] struct {
+// eviction records an item that left the cache so OnEvict can be called after the lock is released.
+type eviction[K comparable, V any] struct {
+	key    K
 	value  V
-	expiry *time.Time
+	reason EvictReason
 }
 
 var (
 	defaultCleanInterval = 5 * time.Minute
+	defaultStatsInterval = time.Minute
+	// expireBatch bounds how many expired items DeleteExpired removes per lock acquisition
+	expireBatch = 1024
 )
 
+var _ ICache[string, any] = (*TTLCache[string, any])(nil)
+
 // New creates a new TTLCache instance
 func New[K comparable, V any](cleanInterval ...time.Duration) ICache[K, V] {
+	var opts Options[K, V]
+	if len(cleanInterval) > 0 {
+		opts.CleanInterval = cleanInterval[0]
+	}
+	return NewWithOptions(opts)
+}
+
+// NewWithOptions creates a new TTLCache instance configured by opts.
+// Unless the janitor is disabled, the cache must be closed to release its background goroutine.
+func NewWithOptions[K comparable, V any](opts Options[K, V]) *TTLCache[K, V] {
 	c := &TTLCache[K, V]{
-		items: make(map[K]*item[V]),
+		items:         make(map[K]*item[K, V]),
+		clock:         opts.Clock,
+		cleanInterval: opts.CleanInterval,
+		janitor:       !opts.DisableJanitor,
+		onEvict:       opts.OnEvict,
+		onStats:       opts.OnStats,
+		statsInterval: opts.StatsInterval,
+		keyCodec:      opts.KeyCodec,
+		valueCodec:    opts.ValueCodec,
+		snapshotPath:  opts.SnapshotPath,
+		sliding:       opts.SlidingExpiration,
+		jitter:        opts.TTLJitter,
+		loader:        opts.Loader,
+		stopCh:        make(chan struct{}),
+	}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+	if c.cleanInterval <= 0 {
+		c.cleanInterval = defaultCleanInterval
+	}
+	if c.statsInterval <= 0 {
+		c.statsInterval = defaultStatsInterval
+	}
+	if c.keyCodec == nil {
+		c.keyCodec = GobCodec[K]{}
+	}
+	if c.valueCodec == nil {
+		c.valueCodec = GobCodec[V]{}
+	}
+	if c.loader != nil {
+		c.refreshAhead = opts.RefreshAhead
+		c.staleGrace = opts.StaleGrace
+	}
+	if opts.MaxCost > 0 {
+		c.maxCost = opts.MaxCost
+		c.evictPolicy = opts.Policy
+		c.policy = c.newPolicy()
+		c.cost = opts.Cost
+		if c.cost == nil {
+			c.cost = defaultCost[V]
+		}
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	if c.snapshotPath != "" {
+		c.snapshotEvery = opts.SnapshotInterval
+		if err := c.LoadFile(c.snapshotPath); err != nil {
+			logger.Logger.Errorf("cache: load snapshot %s failed with: %s", c.snapshotPath, err)
+		}
 	}
 
-	if len(cleanInterval) > 0 {
-		c.cleanInterval = &cleanInterval[0]
-	} else {
-		c.cleanInterval = &defaultCleanInterval
+	if c.janitor || c.onStats != nil || c.snapshotEvery > 0 {
+		c.doneCh = make(chan struct{})
+		go c.runBackground()
 	}
-	go c.cleanupExpiredItems()
 
 	return c
 }
@@ -48,64 +233,274 @@ func New[K comparable, V any](cleanInterval ...time.Duration) ICache[K, V] {
 // Set adds or updates a key-value pair in the cache with optional TTL, if no TTL is specified the item will not expire.
 func (c *TTLCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	now := c.clock.Now()
+	evicted := c.insertLocked(c.newItem(key, value, now, ttl...), now)
+	c.mu.Unlock()
 
-	var expiry *time.Time
-	if len(ttl) > 0 {
-		t := time.Now().Add(ttl[0])
-		expiry = &t
-	}
-	c.items[key] = &item[V]{value: value, expiry: expiry}
+	c.notifySet(key, value)
+	c.notify(evicted...)
 }
 
 // Get retrieves the value associated with the given key.
+// With sliding expiration a hit also pushes the expiry of the item back by its TTL.
+// With refresh-ahead or a stale grace period a hit may trigger a background reload, see Options.
+// With a MaxCost a hit marks the item as recently used.
 func (c *TTLCache[K, V]) Get(key K) (V, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	// Both sliding expiration and the eviction policy update state on a hit
+	exclusive := c.sliding || c.policy != nil
+	if exclusive {
+		c.mu.Lock()
+	} else {
+		c.mu.RLock()
+	}
 
+	var value V
+	var ttl, baseTTL time.Duration
+	now := c.clock.Now()
 	item, found := c.items[key]
-	if !found || (item.expiry != nil && item.expiry.Before(time.Now())) {
+	if found {
+		value, ttl, baseTTL = item.value, item.ttl, item.baseTTL
+	}
+	hit := found && !item.expired(now)
+	stale := found && !hit && c.staleGrace > 0 && !item.expiry.Add(c.staleGrace).Before(now)
+	refresh := stale || (hit && c.refreshAhead > 0 && ttl > 0 && item.expiry.Sub(now) <= c.refreshAhead)
+	if hit && c.sliding && ttl > 0 {
+		c.setExpiryLocked(item, now.Add(ttl))
+	}
+	if hit && c.policy != nil {
+		c.policy.access(key)
+	}
+
+	if exclusive {
+		c.mu.Unlock()
+	} else {
+		c.mu.RUnlock()
+	}
+
+	if refresh {
+		c.refresh(key, baseTTL)
+	}
+	switch {
+	case hit:
+		c.stats.hits.Add(1)
+	case stale:
+		c.stats.staleHits.Add(1)
+	default:
+		c.stats.misses.Add(1)
 		var zeroV V
 		return zeroV, false
 	}
-	return item.value, true
+	return value, true
 }
 
 // Remove deletes the key-value pair with the specified key.
 func (c *TTLCache[K, V]) Remove(key K) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	delete(c.items, key)
+	c.Pop(key)
 }
 
 // Pop removes and returns the value associated with the specified key.
 func (c *TTLCache[K, V]) Pop(key K) (V, bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	now := c.clock.Now()
 	item, found := c.items[key]
 	if found {
-		delete(c.items, key)
-		return item.value, true
+		c.deleteLocked(item)
 	}
+	delete(c.negative, key)
+	c.mu.Unlock()
 
 	var zeroV V
-	return zeroV, false
+	if !found {
+		return zeroV, false
+	}
+	if item.expired(now) {
+		c.notify(eviction[K, V]{key: key, value: item.value, reason: ReasonExpired})
+		return zeroV, false
+	}
+	c.notify(eviction[K, V]{key: key, value: item.value, reason: ReasonRemoved})
+	return item.value, true
 }
 
-// cleanupExpiredItems periodically removes expired items.
-func (c *TTLCache[K, V]) cleanupExpiredItems() {
-	ticker := time.NewTicker(*c.cleanInterval)
-	defer ticker.Stop()
+// DeleteExpired removes all expired items, it is what the janitor runs on every tick.
+// Expired items are popped off the expiry heap in batches, so the lock is never held for a full scan
+// and each removal costs O(log n).
+func (c *TTLCache[K, V]) DeleteExpired() {
+	for {
+		var evicted []eviction[K, V]
 
-	for range ticker.C {
 		c.mu.Lock()
-		for key, item := range c.items {
-			if item.expiry != nil && item.expiry.Before(time.Now()) {
-				delete(c.items, key)
+		now := c.clock.Now()
+		// Stale items are kept until their grace period is over
+		purgeAt := now.Add(-c.staleGrace)
+		for len(evicted) < expireBatch {
+			it := c.expiries.peek()
+			if it == nil || !it.expired(purgeAt) {
+				break
+			}
+			c.deleteLocked(it)
+			evicted = append(evicted, eviction[K, V]{key: it.key, value: it.value, reason: ReasonExpired})
+		}
+		more := len(evicted) == expireBatch
+		if !more {
+			// Cached loader errors are few and short-lived, a scan is cheaper than indexing them
+			for key, negative := range c.negative {
+				if negative.expiry.Before(now) {
+					delete(c.negative, key)
+				}
 			}
 		}
 		c.mu.Unlock()
+
+		c.notify(evicted...)
+		if !more {
+			return
+		}
+	}
+}
+
+// Close stops the janitor, the stats hook and background refreshes and waits for the janitor to exit. The cache stays usable afterwards,
+// but expired items are no longer purged in the background. If a SnapshotPath is set, Close saves a final
+// snapshot and returns its error. Close is idempotent.
+func (c *TTLCache[K, V]) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		close(c.stopCh)
+		if c.doneCh != nil {
+			<-c.doneCh
+		}
+		if c.snapshotPath != "" {
+			c.closeErr = c.SaveFile(c.snapshotPath)
+		}
+		c.watchers.close()
+	})
+	return c.closeErr
+}
+
+// newItem creates an item set at now with an optional TTL.
+func (c *TTLCache[K, V]) newItem(key K, value V, now time.Time, ttl ...time.Duration) *item[K, V] {
+	it := &item[K, V]{key: key, value: value, index: -1}
+	if len(ttl) > 0 {
+		it.baseTTL = ttl[0]
+		it.ttl = c.jittered(ttl[0])
+		it.expiry = now.Add(it.ttl)
+	}
+	return it
+}
+
+// costOf returns the cost of value, never negative.
+func (c *TTLCache[K, V]) costOf(value V) int64 {
+	if cost := c.cost(value); cost > 0 {
+		return cost
+	}
+	return 0
+}
+
+// insertLocked stores it under its key at now and returns the evictions it caused: the item it replaced, if any,
+// and the items dropped to stay within MaxCost, which may include it. The caller must hold the write lock.
+func (c *TTLCache[K, V]) insertLocked(it *item[K, V], now time.Time) []eviction[K, V] {
+	var evicted []eviction[K, V]
+	if old, found := c.items[it.key]; found {
+		c.deleteLocked(old)
+		evicted = append(evicted, old.replaced(now))
+	}
+	c.items[it.key] = it
+	if !it.expiry.IsZero() {
+		heap.Push(&c.expiries, it)
+	}
+	c.tagLocked(it)
+	delete(c.negative, it.key)
+
+	if c.policy != nil {
+		for _, key := range c.policy.add(it.key, c.costOf(it.value)) {
+			victim := c.items[key]
+			c.deleteLocked(victim)
+			evicted = append(evicted, eviction[K, V]{key: key, value: victim.value, reason: ReasonEvicted})
+		}
+	}
+	return evicted
+}
+
+// deleteLocked removes it from the cache and from its tag indexes. The caller must hold the write lock.
+func (c *TTLCache[K, V]) deleteLocked(it *item[K, V]) {
+	delete(c.items, it.key)
+	if it.index >= 0 {
+		heap.Remove(&c.expiries, it.index)
 	}
+	c.untagLocked(it)
+	if c.policy != nil {
+		c.policy.remove(it.key)
+	}
+}
+
+// notify records each eviction in the stats and calls the OnEvict callback, it must not be called with the lock held.
+func (c *TTLCache[K, V]) notify(evicted ...eviction[K, V]) {
+	for _, e := range evicted {
+		c.stats.evicted(e.reason)
+		if c.onEvict != nil {
+			c.onEvict(e.key, e.value, e.reason)
+		}
+		if typ, ok := eventType(e.reason); ok {
+			c.watchers.publish(Event[K, V]{Type: typ, Key: e.key, Value: e.value})
+		}
+	}
+}
+
+// notifySet records that key was set to value, it is called outside the lock before the evictions the write caused.
+func (c *TTLCache[K, V]) notifySet(key K, value V) {
+	c.stats.sets.Add(1)
+	c.watchers.publish(Event[K, V]{Type: EventSet, Key: key, Value: value})
+}
+
+// runBackground runs the janitor, the stats hook and periodic snapshots until the cache is closed or its context is done.
+func (c *TTLCache[K, V]) runBackground() {
+	defer close(c.doneCh)
+
+	// A nil channel blocks forever, so a disabled task never fires
+	var cleanC, statsC, snapshotC <-chan time.Time
+	if c.janitor {
+		ticker := time.NewTicker(c.cleanInterval)
+		defer ticker.Stop()
+		cleanC = ticker.C
+	}
+	if c.onStats != nil {
+		ticker := time.NewTicker(c.statsInterval)
+		defer ticker.Stop()
+		statsC = ticker.C
+	}
+	if c.snapshotEvery > 0 {
+		ticker := time.NewTicker(c.snapshotEvery)
+		defer ticker.Stop()
+		snapshotC = ticker.C
+	}
+
+	for {
+		select {
+		case <-cleanC:
+			c.DeleteExpired()
+		case <-statsC:
+			c.onStats(c.Stats())
+		case <-snapshotC:
+			if err := c.SaveFile(c.snapshotPath); err != nil {
+				logger.Logger.Errorf("cache: save snapshot %s failed with: %s", c.snapshotPath, err)
+			}
+		case <-c.stopCh:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// replaced returns the eviction of an item overwritten at now, an overwritten item that had already expired counts as expired.
+func (i *item[K, V]) replaced(now time.Time) eviction[K, V] {
+	reason := ReasonReplaced
+	if i.expired(now) {
+		reason = ReasonExpired
+	}
+	return eviction[K, V]{key: i.key, value: i.value, reason: reason}
+}
+
+// expired reports whether the item has a TTL that has passed at now.
+func (i *item[K, V]) expired(now time.Time) bool {
+	return !i.expiry.IsZero() && i.expiry.Before(now)
 }
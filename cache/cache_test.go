@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"strconv"
 	"sync"
 	"testing"
@@ -99,6 +100,88 @@ func TestConcurrentSetAndGet(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCloseStopsJanitor(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{CleanInterval: time.Millisecond})
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+	select {
+	case <-c.doneCh:
+	default:
+		t.Error("Expected janitor to exit after Close")
+	}
+	// Close must be idempotent
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close returned %v", err)
+	}
+}
+
+func TestContextStopsJanitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewWithOptions(Options[string, int]{CleanInterval: time.Millisecond, Context: ctx})
+	cancel()
+	select {
+	case <-c.doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("Expected janitor to exit after context cancel")
+	}
+}
+
+func TestDisableJanitor(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+	if c.doneCh != nil {
+		t.Fatal("Expected no janitor")
+	}
+	c.Set("key", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if len(c.items) != 1 {
+		t.Fatalf("Expected expired item to stay until DeleteExpired, got %d items", len(c.items))
+	}
+	c.DeleteExpired()
+	if len(c.items) != 0 {
+		t.Errorf("Expected DeleteExpired to purge the item, got %d items", len(c.items))
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	var mu sync.Mutex
+	reasons := make(map[string]EvictReason)
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			reasons[key] = reason
+		},
+	})
+	defer c.Close()
+
+	c.Set("replaced", 1)
+	c.Set("replaced", 2)
+	c.Set("removed", 1)
+	c.Remove("removed")
+	c.Set("popped", 1)
+	c.Pop("popped")
+	c.Set("expired", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	c.DeleteExpired()
+
+	want := map[string]EvictReason{
+		"replaced": ReasonReplaced,
+		"removed":  ReasonRemoved,
+		"popped":   ReasonRemoved,
+		"expired":  ReasonExpired,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for key, reason := range want {
+		if got, ok := reasons[key]; !ok || got != reason {
+			t.Errorf("Expected %s to be evicted with %v, got %v (called: %v)", key, reason, got, ok)
+		}
+	}
+}
+
 func BenchmarkConcurrentSet10(b *testing.B) {
 	benchmarkConcurrentSet(b, 10)
 }
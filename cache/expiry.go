@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Clock tells the cache the current time, tests can inject a fake clock to advance time deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// expiryHeap is a min-heap of the expiring items ordered by expiry, so the janitor only
+// visits items that have actually expired instead of scanning the whole map.
+type expiryHeap[K comparable, V any] []*item[K, V]
+
+var _ heap.Interface = (*expiryHeap[string, any])(nil)
+
+func (h expiryHeap[K, V]) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap[K, V]) Less(i, j int) bool {
+	return h[i].expiry.Before(h[j].expiry)
+}
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	it := x.(*item[K, V])
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
+
+// peek returns the item that expires first, or nil if no item expires.
+func (h expiryHeap[K, V]) peek() *item[K, V] {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
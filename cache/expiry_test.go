@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when the test advances it.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestFakeClockTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.Set("key", 1, time.Minute)
+	clock.Advance(59 * time.Second)
+	if _, found := c.Get("key"); !found {
+		t.Fatal("Expected key to be alive before its TTL")
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := c.Get("key"); found {
+		t.Fatal("Expected key to be expired after its TTL")
+	}
+}
+
+func TestDeleteExpiredUsesHeap(t *testing.T) {
+	clock := newFakeClock()
+	var mu sync.Mutex
+	var expired []string
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			if reason == ReasonExpired {
+				expired = append(expired, key)
+			}
+		},
+	})
+	defer c.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	ttls := make(map[string]time.Duration)
+	for i := 0; i < 3*expireBatch; i++ {
+		key := "key" + strconv.Itoa(i)
+		ttl := time.Duration(rng.Intn(100)+1) * time.Second
+		ttls[key] = ttl
+		c.Set(key, i, ttl)
+	}
+	c.Set("forever", 0)
+	// Overwrite and remove some keys, their stale heap entries must go with them
+	c.Set("key0", 0, time.Hour)
+	ttls["key0"] = time.Hour
+	c.Remove("key1")
+	delete(ttls, "key1")
+
+	clock.Advance(50*time.Second + time.Millisecond)
+	c.DeleteExpired()
+
+	for key, ttl := range ttls {
+		_, found := c.items[key]
+		if alive := ttl > 50*time.Second; found != alive {
+			t.Fatalf("Expected %s with ttl %v to be present=%v", key, ttl, alive)
+		}
+	}
+	if _, found := c.items["forever"]; !found {
+		t.Error("Expected item without TTL to be kept")
+	}
+	if len(c.expiries) != len(c.items)-1 {
+		t.Errorf("Expected heap to track %d expiring items, got %d", len(c.items)-1, len(c.expiries))
+	}
+	for i, it := range c.expiries {
+		if it.index != i {
+			t.Fatalf("Expected heap index %d, got %d", i, it.index)
+		}
+		if i > 0 && it.expiry.Before(c.expiries[(i-1)/2].expiry) {
+			t.Fatalf("Heap order violated at %d", i)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(expired)+len(c.items)-1 != len(ttls) {
+		t.Errorf("Expected %d expirations, got %d", len(ttls)-len(c.items)+1, len(expired))
+	}
+}
+
+func BenchmarkDeleteExpired(b *testing.B) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[int, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+	// A large cache where only a handful of items expire on each run
+	for i := 0; i < 1_000_000; i++ {
+		c.Set(i, i, time.Duration(i)*time.Millisecond+time.Hour)
+	}
+	clock.Advance(time.Hour)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clock.Advance(time.Millisecond)
+		c.DeleteExpired()
+	}
+}
@@ -0,0 +1,10 @@
+//go:build go1.24
+
+package cache
+
+import "hash/maphash"
+
+// hashKey hashes any comparable key with the given seed.
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	return maphash.Comparable(seed, key)
+}
@@ -0,0 +1,43 @@
+//go:build !go1.24
+
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"reflect"
+)
+
+// hashKey hashes any comparable key with the given seed. maphash.Comparable needs Go 1.24,
+// so common key types are hashed directly, pointers and channels by address and anything else through its
+// printed form.
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	var buf [8]byte
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+	case int32:
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+	case uint:
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+	case uint32:
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+	case uint64:
+		binary.LittleEndian.PutUint64(buf[:], k)
+	default:
+		// Compared by address, while their printed form shows what they point to, which may change
+		v := reflect.ValueOf(key)
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+			binary.LittleEndian.PutUint64(buf[:], uint64(v.Pointer()))
+		default:
+			return maphash.String(seed, fmt.Sprintf("%#v", key))
+		}
+	}
+	return maphash.Bytes(seed, buf[:])
+}
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HealthCheck returns a check that sets key to value in c, reads it back and removes it, to register as a
+// readiness check of the http server. key should not be used otherwise, the round trip goes through OnEvict,
+// watchers and invalidation like any other key.
+func HealthCheck[K comparable, V any](c ICache[K, V], key K, value V) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		c.Set(key, value, time.Minute)
+		defer c.Remove(key)
+		if _, found := c.Get(key); !found {
+			return fmt.Errorf("cache: health check key %v not found after Set", key)
+		}
+		return ctx.Err()
+	}
+}
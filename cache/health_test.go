@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	c := New[string, int]()
+	defer c.Close()
+	check := HealthCheck[string, int](c, "__health", 1)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected the round trip to succeed, got %v", err)
+	}
+	if _, found := c.Get("__health"); found {
+		t.Errorf("Expected the health key to be removed")
+	}
+
+	bc := NewByteCache(ByteCacheOptions{MaxBytes: 1 << 10, Shards: 1})
+	defer bc.Close()
+	if err := HealthCheck[string, []byte](bc, "__health", make([]byte, 1<<11))(context.Background()); err == nil {
+		t.Errorf("Expected an error when the value cannot be stored")
+	}
+}
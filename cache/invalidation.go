@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"sync"
+)
+
+// Transport carries invalidation messages between the instances of an InvalidationBus.
+// Broadcast sends msg to every other instance. Listen registers the function called with each message received,
+// it is called once before any message is broadcast. Close stops receiving.
+type Transport interface {
+	Broadcast(msg []byte) error
+	Listen(fn func(msg []byte)) error
+	Close() error
+}
+
+// signature returns the HMAC-SHA256 of msg with secret, which transports attach to authenticate messages.
+func signature(secret, msg []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+// InvalidationBusOptions configures an InvalidationBus.
+// Transport carries the messages, it is required and closed with the bus.
+// KeyCodec encodes keys in messages, nil means the raw bytes for string keys and JSON for anything else.
+// ID identifies this instance so it ignores its own messages, empty means a random ID.
+type InvalidationBusOptions[K comparable] struct {
+	Transport Transport
+	KeyCodec  Codec[K]
+	ID        string
+}
+
+// InvalidationBus propagates removals between the local caches of several instances.
+// Remove and InvalidateTag apply to the local cache and are broadcast to the peers, which apply them to theirs.
+// It also implements Notifier, so it can invalidate the first tier of TieredCache peers.
+type InvalidationBus[K comparable, V any] struct {
+	cache     *TTLCache[K, V]
+	transport Transport
+	keyCodec  Codec[K]
+	id        string
+	mu        sync.RWMutex
+	subs      map[int]func(key K)
+	nextSub   int
+}
+
+// invalidation is the message broadcast between instances.
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   [][]byte `json:"keys,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+var _ Notifier[string] = (*InvalidationBus[string, any])(nil)
+
+// NewInvalidationBus creates an InvalidationBus for cache and starts listening on the transport.
+// cache may be nil when the bus is only used as the Notifier of a TieredCache.
+func NewInvalidationBus[K comparable, V any](cache *TTLCache[K, V], opts InvalidationBusOptions[K]) (*InvalidationBus[K, V], error) {
+	if opts.Transport == nil {
+		return nil, fmt.Errorf("cache: invalidation bus needs a transport")
+	}
+	b := &InvalidationBus[K, V]{
+		cache:     cache,
+		transport: opts.Transport,
+		keyCodec:  opts.KeyCodec,
+		id:        opts.ID,
+		subs:      make(map[int]func(key K)),
+	}
+	if b.keyCodec == nil {
+		b.keyCodec = defaultKeyCodec[K]()
+	}
+	if b.id == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		b.id = hex.EncodeToString(id)
+	}
+	if err := b.transport.Listen(b.receive); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Remove deletes keys from the local cache and broadcasts their removal to the peers.
+func (b *InvalidationBus[K, V]) Remove(keys ...K) error {
+	if b.cache != nil {
+		b.cache.RemoveMany(keys...)
+	}
+	return b.broadcastKeys(keys...)
+}
+
+// InvalidateTag removes the items tagged with tag from the local cache, broadcasts the invalidation to the peers
+// and returns how many local items were removed.
+func (b *InvalidationBus[K, V]) InvalidateTag(tag string) (int, error) {
+	n := 0
+	if b.cache != nil {
+		n = b.cache.InvalidateTag(tag)
+	}
+	return n, b.broadcast(invalidation{Origin: b.id, Tags: []string{tag}})
+}
+
+// Publish broadcasts the removal of key to the peers without touching the local cache.
+func (b *InvalidationBus[K, V]) Publish(key K) error {
+	return b.broadcastKeys(key)
+}
+
+// Subscribe registers fn for the keys removed by peers and returns a function that cancels the subscription.
+func (b *InvalidationBus[K, V]) Subscribe(fn func(key K)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextSub
+	b.nextSub++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Close closes the transport.
+func (b *InvalidationBus[K, V]) Close() error {
+	return b.transport.Close()
+}
+
+func (b *InvalidationBus[K, V]) broadcastKeys(keys ...K) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	msg := invalidation{Origin: b.id, Keys: make([][]byte, len(keys))}
+	for i, key := range keys {
+		k, err := b.keyCodec.Encode(key)
+		if err != nil {
+			return fmt.Errorf("encode key %v: %w", key, err)
+		}
+		msg.Keys[i] = k
+	}
+	return b.broadcast(msg)
+}
+
+func (b *InvalidationBus[K, V]) broadcast(msg invalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.transport.Broadcast(data)
+}
+
+// receive applies a message from a peer, messages are not acknowledged so errors are logged.
+func (b *InvalidationBus[K, V]) receive(data []byte) {
+	var msg invalidation
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.Logger.Errorf("cache: decode invalidation failed with: %s", err)
+		return
+	}
+	if msg.Origin == b.id {
+		return
+	}
+	keys := make([]K, 0, len(msg.Keys))
+	for _, k := range msg.Keys {
+		key, err := b.keyCodec.Decode(k)
+		if err != nil {
+			logger.Logger.Errorf("cache: decode invalidated key failed with: %s", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if b.cache != nil {
+		b.cache.RemoveMany(keys...)
+		for _, tag := range msg.Tags {
+			b.cache.InvalidateTag(tag)
+		}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subs {
+		for _, key := range keys {
+			fn(key)
+		}
+	}
+}
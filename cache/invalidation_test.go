@@ -0,0 +1,283 @@
+package cache
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// busPeer is a cache with its invalidation bus.
+type busPeer struct {
+	cache *TTLCache[string, string]
+	bus   *InvalidationBus[string, string]
+}
+
+func newBusPeer(t *testing.T, transport Transport) busPeer {
+	c := NewWithOptions(Options[string, string]{DisableJanitor: true})
+	bus, err := NewInvalidationBus(c, InvalidationBusOptions[string]{Transport: transport})
+	if err != nil {
+		t.Fatalf("NewInvalidationBus failed: %v", err)
+	}
+	t.Cleanup(func() {
+		bus.Close()
+		c.Close()
+	})
+	return busPeer{cache: c, bus: bus}
+}
+
+// testPropagation checks that the removals and tag invalidations on a reach b.
+func testPropagation(t *testing.T, a, b busPeer) {
+	t.Helper()
+	for _, p := range []busPeer{a, b} {
+		p.cache.Set("user", "alice")
+		p.cache.SetWithTags("feed", "items", []string{"feeds"})
+		p.cache.Set("kept", "value")
+	}
+
+	if err := a.bus.Remove("user"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, found := a.cache.Get("user"); found {
+		t.Error("Expected Remove to apply locally")
+	}
+	waitFor(t, func() bool {
+		_, found := b.cache.Get("user")
+		return !found
+	})
+
+	n, err := a.bus.InvalidateTag("feeds")
+	if err != nil || n != 1 {
+		t.Fatalf("Expected to invalidate 1 local item, got %d, %v", n, err)
+	}
+	waitFor(t, func() bool {
+		_, found := b.cache.Get("feed")
+		return !found
+	})
+	if _, found := b.cache.Get("kept"); !found {
+		t.Error("Expected other keys to be kept")
+	}
+}
+
+func TestWebhookInvalidation(t *testing.T) {
+	var peers []busPeer
+	var servers []*httptest.Server
+	transports := make([]*WebhookTransport, 2)
+	for i := range transports {
+		i := i
+		servers = append(servers, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			transports[i].ServeHTTP(w, r)
+		})))
+		defer servers[i].Close()
+	}
+	transports[0] = NewWebhookTransport([]string{servers[1].URL}, nil)
+	transports[1] = NewWebhookTransport([]string{servers[0].URL}, nil)
+	for _, transport := range transports {
+		peers = append(peers, newBusPeer(t, transport))
+	}
+	testPropagation(t, peers[0], peers[1])
+	testPropagation(t, peers[1], peers[0])
+
+	resp, err := http.Get(servers[0].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for a GET, got %d", resp.StatusCode)
+	}
+
+	servers[1].Close()
+	if err := peers[0].bus.Remove("user"); err == nil {
+		t.Error("Expected an error when a peer is down")
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	var received []string
+	receiver := NewWebhookTransport(nil, nil, WebhookOptions{Secret: []byte("secret")})
+	receiver.Listen(func(msg []byte) {
+		received = append(received, string(msg))
+	})
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if err := NewWebhookTransport([]string{server.URL}, nil, WebhookOptions{Secret: []byte("secret")}).Broadcast([]byte("signed")); err != nil {
+		t.Errorf("Expected a signed message to be accepted, got %v", err)
+	}
+	if err := NewWebhookTransport([]string{server.URL}, nil, WebhookOptions{Secret: []byte("other")}).Broadcast([]byte("forged")); err == nil {
+		t.Error("Expected a message signed with another secret to be rejected")
+	}
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader("unsigned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unsigned message, got %d", resp.StatusCode)
+	}
+	if len(received) != 1 || received[0] != "signed" {
+		t.Errorf("Expected only the signed message, got %v", received)
+	}
+}
+
+func TestUDPInvalidation(t *testing.T) {
+	a, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0", Peers: []string{a.LocalAddr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a also sends to itself, as a multicast member would, its own messages must be ignored
+	a.AddPeer(b.LocalAddr().String())
+	a.AddPeer(a.LocalAddr().String())
+
+	peerA, peerB := newBusPeer(t, a), newBusPeer(t, b)
+	testPropagation(t, peerA, peerB)
+	testPropagation(t, peerB, peerA)
+}
+
+func TestUDPSecret(t *testing.T) {
+	receiver, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0", Secret: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+	received := make(chan string, 4)
+	receiver.Listen(func(msg []byte) {
+		received <- string(msg)
+	})
+	peer := receiver.LocalAddr().String()
+
+	forger, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0", Peers: []string{peer}, Secret: []byte("other")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forger.Close()
+	forger.Broadcast([]byte("forged"))
+	unsigned, err := net.Dial("udp", peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsigned.Close()
+	unsigned.Write([]byte("unsigned"))
+
+	sender, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0", Peers: []string{peer}, Secret: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err := sender.Broadcast([]byte("signed")); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+
+	// Datagrams over loopback arrive in order, so the others have been dropped once the signed one is received
+	select {
+	case msg := <-received:
+		if msg != "signed" {
+			t.Errorf("Expected only the signed message, got %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the signed message to be received")
+	}
+}
+
+func TestUDPMulticastInvalidation(t *testing.T) {
+	lo, err := multicastLoopback()
+	if err != nil {
+		t.Skipf("No multicast loopback interface: %v", err)
+	}
+	opts := UDPTransportOptions{Addr: "239.255.42.99:47946", Interface: lo}
+	a, err := NewUDPTransport(opts)
+	if err != nil {
+		t.Skipf("Multicast is not available: %v", err)
+	}
+	b, err := NewUDPTransport(opts)
+	if err != nil {
+		a.Close()
+		t.Skipf("Multicast is not available: %v", err)
+	}
+	peerA, peerB := newBusPeer(t, a), newBusPeer(t, b)
+
+	peerB.cache.Set("probe", "value")
+	peerA.bus.Remove("probe")
+	received := false
+	for i := 0; i < 100 && !received; i++ {
+		_, found := peerB.cache.Get("probe")
+		received = !found
+		if !received {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if !received {
+		t.Skip("Multicast datagrams are not looped back on this host")
+	}
+	testPropagation(t, peerA, peerB)
+}
+
+func TestInvalidationBusNotifier(t *testing.T) {
+	// One UDP transport per instance, each tiered cache drops its L1 entry on a peer's write
+	a, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewUDPTransport(UDPTransportOptions{Addr: "127.0.0.1:0", Peers: []string{a.LocalAddr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.AddPeer(b.LocalAddr().String())
+	busA, _ := NewInvalidationBus[string, string](nil, InvalidationBusOptions[string]{Transport: a})
+	busB, _ := NewInvalidationBus[string, string](nil, InvalidationBusOptions[string]{Transport: b})
+	defer busA.Close()
+	defer busB.Close()
+
+	l2 := NewWithOptions(Options[string, string]{DisableJanitor: true})
+	defer l2.Close()
+	newInstance := func(bus *InvalidationBus[string, string]) *TieredCache[string, string] {
+		return NewTiered[string, string](sharedL2[string, string]{l2}, TieredOptions[string, string]{
+			L1:       Options[string, string]{DisableJanitor: true},
+			Notifier: bus,
+		})
+	}
+	ta, tb := newInstance(busA), newInstance(busB)
+	defer ta.Close()
+	defer tb.Close()
+
+	ta.Set("user", "v1")
+	if value, _ := tb.Get("user"); value != "v1" {
+		t.Fatalf("Expected v1, got %v", value)
+	}
+	ta.Set("user", "v2")
+	waitFor(t, func() bool {
+		_, found := tb.L1().Get("user")
+		return !found
+	})
+	if value, _ := tb.Get("user"); value != "v2" {
+		t.Errorf("Expected v2, got %v", value)
+	}
+}
+
+func TestInvalidationBusNeedsTransport(t *testing.T) {
+	if _, err := NewInvalidationBus[string, string](nil, InvalidationBusOptions[string]{}); err == nil {
+		t.Error("Expected an error without a transport")
+	}
+}
+
+// multicastLoopback returns the loopback interface if it supports multicast.
+func multicastLoopback() (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagLoopback != 0 && ifaces[i].Flags&net.FlagMulticast != 0 {
+			return &ifaces[i], nil
+		}
+	}
+	return nil, errors.New("no loopback interface with multicast")
+}
@@ -0,0 +1,24 @@
+//go:build go1.23
+
+package cache
+
+import "iter"
+
+// All returns an iterator over the unexpired items of the cache, for use with range-over-func.
+// Like Range it iterates over a snapshot, so the loop body may safely use the cache.
+func (c *TTLCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Range(yield)
+	}
+}
+
+// KeysSeq returns an iterator over the keys of the unexpired items of the cache.
+func (c *TTLCache[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for _, key := range c.Keys() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build go1.23
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.SetMany(map[string]int{"a": 1, "b": 2, "c": 3})
+	c.Set("expired", 100, time.Second)
+	clock.Advance(2 * time.Second)
+
+	sum := 0
+	for _, value := range c.All() {
+		sum += value
+	}
+	if sum != 6 {
+		t.Errorf("Expected to iterate over unexpired items only, sum %d", sum)
+	}
+
+	n := 0
+	for key := range c.KeysSeq() {
+		c.Remove(key)
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected break to stop the iteration after 2 removals, %d items left", c.Len())
+	}
+}
@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"sync"
+	"time"
+)
+
+// Loader loads the value of a key on a cache miss.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// LoadOptions configures a single GetOrLoad call.
+// TTL is the TTL of the loaded value, zero means the value will not expire.
+// ErrorTTL caches loader errors (e.g. not found) for the given duration, zero means errors are not cached.
+// CacheError decides which errors are cached when ErrorTTL is set, nil means all errors except context errors.
+type LoadOptions struct {
+	TTL        time.Duration
+	ErrorTTL   time.Duration
+	CacheError func(err error) bool
+}
+
+type negativeItem struct {
+	err    error
+	expiry time.Time
+}
+
+// GetOrLoad returns the cached value of key, on a miss it calls loader and caches its result.
+// A nil loader means the Loader of the cache options.
+// Concurrent misses on the same key share a single loader call. A caller whose ctx is done
+// stops waiting and gets ctx.Err(), the loader itself is only cancelled once every caller has given up.
+func (c *TTLCache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V], opts ...LoadOptions) (V, error) {
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+	if loader == nil {
+		loader = c.loader
+	}
+	if loader == nil {
+		var zeroV V
+		return zeroV, errors.New("cache: no loader")
+	}
+	if err, found := c.cachedErr(key); found {
+		var zeroV V
+		return zeroV, err
+	}
+
+	var opt LoadOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	return c.loads.do(ctx, key, func(ctx context.Context) (V, error) {
+		// Another call may have filled the key while we were joining the flight, the miss is already counted
+		if value, found := c.peek(key); found {
+			return value, nil
+		}
+		start := time.Now()
+		value, err := loader(ctx, key)
+		c.stats.loaded(start, err)
+		if err != nil {
+			if opt.ErrorTTL > 0 && ctx.Err() == nil && (opt.CacheError == nil || opt.CacheError(err)) {
+				c.setErr(key, err, opt.ErrorTTL)
+			}
+			return value, err
+		}
+		if opt.TTL > 0 {
+			c.Set(key, value, opt.TTL)
+		} else {
+			c.Set(key, value)
+		}
+		return value, nil
+	})
+}
+
+// refresh reloads key in the background with the cache loader unless a load of key is already in flight.
+// The stale value stays in the cache if the reload fails.
+func (c *TTLCache[K, V]) refresh(key K, ttl time.Duration) {
+	c.loads.start(c.ctx, key, func(ctx context.Context) (V, error) {
+		start := time.Now()
+		value, err := c.loader(ctx, key)
+		c.stats.loaded(start, err)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Logger.Errorf("cache: refresh key %v failed with: %s", key, err)
+			}
+			return value, err
+		}
+		if ttl > 0 {
+			c.setRefreshed(key, value, ttl)
+		} else {
+			c.setRefreshed(key, value)
+		}
+		return value, nil
+	})
+}
+
+// setRefreshed is Set for a value reloaded in the background, the item keeps the tags of the one it replaces.
+func (c *TTLCache[K, V]) setRefreshed(key K, value V, ttl ...time.Duration) {
+	c.mu.Lock()
+	now := c.clock.Now()
+	it := c.newItem(key, value, now, ttl...)
+	if old, found := c.items[key]; found {
+		it.tags = old.tags
+	}
+	evicted := c.insertLocked(it, now)
+	c.mu.Unlock()
+
+	c.notifySet(key, value)
+	c.notify(evicted...)
+}
+
+// peek returns the unexpired value of key without recording stats or updating the expiry and eviction policy.
+func (c *TTLCache[K, V]) peek(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, found := c.items[key]
+	if !found || it.expired(c.clock.Now()) {
+		var zeroV V
+		return zeroV, false
+	}
+	return it.value, true
+}
+
+// cachedErr returns the unexpired loader error cached for key.
+func (c *TTLCache[K, V]) cachedErr(key K) (error, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	negative, found := c.negative[key]
+	if !found || negative.expiry.Before(c.clock.Now()) {
+		return nil, false
+	}
+	return negative.err, true
+}
+
+func (c *TTLCache[K, V]) setErr(key K, err error, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.negative == nil {
+		c.negative = make(map[K]negativeItem)
+	}
+	c.negative[key] = negativeItem{err: err, expiry: c.clock.Now().Add(ttl)}
+}
+
+// group de-duplicates concurrent loads of the same key, the zero value is ready to use.
+type group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// call is an in-flight or completed load shared by all of its waiters.
+type call[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do runs fn once for all concurrent callers of the same key and returns its result.
+func (g *group[K, V]) do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	cl, found := g.calls[key]
+	if !found {
+		// The load must outlive the caller that started it, it keeps ctx values but not its cancellation
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &call[V]{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = cl
+		go g.run(loadCtx, key, cl, fn)
+	}
+	cl.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		cl.waiters--
+		if cl.waiters == 0 {
+			cl.cancel()
+			// Let the next caller start a fresh load instead of joining a cancelled one
+			if g.calls[key] == cl {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		var zeroV V
+		return zeroV, ctx.Err()
+	}
+}
+
+// start runs fn for key in the background unless a load of key is already in flight, it does not wait for the result.
+// The background load counts as a waiter, so callers joining it through do cannot cancel it.
+func (g *group[K, V]) start(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	if _, found := g.calls[key]; found {
+		return
+	}
+	loadCtx, cancel := context.WithCancel(ctx)
+	cl := &call[V]{done: make(chan struct{}), cancel: cancel, waiters: 1}
+	g.calls[key] = cl
+	go g.run(loadCtx, key, cl, fn)
+}
+
+func (g *group[K, V]) run(ctx context.Context, key K, cl *call[V], fn func(ctx context.Context) (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			cl.err = fmt.Errorf("cache: loader panic: %v", r)
+		}
+		g.mu.Lock()
+		if g.calls[key] == cl {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		cl.cancel()
+		close(cl.done)
+	}()
+	cl.value, cl.err = fn(ctx)
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCoalescesMisses(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), "hot", loader)
+			if err != nil || value != 42 {
+				t.Errorf("Expected 42, got %v (err: %v)", value, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected loader to be called once, got %d", calls)
+	}
+	if value, found := c.Get("hot"); !found || value != 42 {
+		t.Errorf("Expected loaded value to be cached, got %v", value)
+	}
+}
+
+func TestGetOrLoadTTL(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	loader := func(ctx context.Context, key string) (int, error) { return 1, nil }
+	if _, err := c.GetOrLoad(context.Background(), "key", loader, LoadOptions{TTL: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, found := c.Get("key"); found {
+		t.Error("Expected loaded value to expire")
+	}
+}
+
+func TestGetOrLoadCachesErrors(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	errNotFound := errors.New("not found")
+	var calls int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, errNotFound
+	}
+	opts := LoadOptions{ErrorTTL: 20 * time.Millisecond}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "missing", loader, opts); !errors.Is(err, errNotFound) {
+			t.Fatalf("Expected errNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected cached error to suppress reloads, loader called %d times", calls)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	c.GetOrLoad(context.Background(), "missing", loader, opts)
+	if calls != 2 {
+		t.Errorf("Expected reload after ErrorTTL, loader called %d times", calls)
+	}
+
+	// Set overrides a cached error
+	c.Set("missing", 7)
+	if value, err := c.GetOrLoad(context.Background(), "missing", loader, opts); err != nil || value != 7 {
+		t.Errorf("Expected 7, got %v (err: %v)", value, err)
+	}
+}
+
+func TestGetOrLoadContextCancel(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	loaderCancelled := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		<-ctx.Done()
+		close(loaderCancelled)
+		return 0, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrLoad(ctx, "slow", loader, LoadOptions{ErrorTTL: time.Minute}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-loaderCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected loader to be cancelled once every caller gave up")
+	}
+	if _, found := c.cachedErr("slow"); found {
+		t.Error("Expected context errors not to be cached")
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	loader := func(ctx context.Context, key string) (int, error) { panic("boom") }
+	if _, err := c.GetOrLoad(context.Background(), "key", loader); err == nil {
+		t.Error("Expected loader panic to be returned as an error")
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import "container/list"
+
+// EvictionPolicy chooses which items a cache with a MaxCost evicts to stay under it.
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used items.
+	PolicyLRU EvictionPolicy = iota
+	// PolicyTinyLFU is W-TinyLFU: new items go through a small LRU window and are only admitted into the main cache
+	// if they are used more often than the item they would evict, so one-off scans cannot flush the hot items.
+	PolicyTinyLFU
+)
+
+// policy decides which items to evict when the cache goes over its MaxCost.
+// add records a new key with its cost and returns the keys to evict to get back under the budget,
+// which may include key itself when it is larger than the whole budget.
+// access records a hit, remove forgets a key that left the cache, cost is the total cost tracked.
+// The cache calls it with the write lock held, so implementations need no locking of their own.
+type policy[K comparable] interface {
+	add(key K, cost int64) (victims []K)
+	access(key K)
+	remove(key K)
+	cost() int64
+}
+
+// newPolicy creates the eviction policy of a cache with a MaxCost.
+func (c *TTLCache[K, V]) newPolicy() policy[K] {
+	if c.evictPolicy == PolicyTinyLFU {
+		return newTinyLFU[K](c.maxCost)
+	}
+	return newLRUPolicy[K](c.maxCost)
+}
+
+// lruPolicy evicts the least recently used keys first.
+type lruPolicy[K comparable] struct {
+	maxCost int64
+	total   int64
+	order   *list.List // front is the most recently used
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable] struct {
+	key  K
+	cost int64
+}
+
+func newLRUPolicy[K comparable](maxCost int64) *lruPolicy[K] {
+	return &lruPolicy[K]{maxCost: maxCost, order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+func (p *lruPolicy[K]) add(key K, cost int64) []K {
+	p.remove(key)
+	if cost > p.maxCost {
+		// Evicting everything else would not make room, so only the oversized key goes
+		return []K{key}
+	}
+	p.entries[key] = p.order.PushFront(&lruEntry[K]{key: key, cost: cost})
+	p.total += cost
+
+	var victims []K
+	for p.total > p.maxCost {
+		e := p.order.Back()
+		victim := e.Value.(*lruEntry[K])
+		p.order.Remove(e)
+		delete(p.entries, victim.key)
+		p.total -= victim.cost
+		victims = append(victims, victim.key)
+	}
+	return victims
+}
+
+func (p *lruPolicy[K]) access(key K) {
+	if e, found := p.entries[key]; found {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if e, found := p.entries[key]; found {
+		p.order.Remove(e)
+		delete(p.entries, key)
+		p.total -= e.Value.(*lruEntry[K]).cost
+	}
+}
+
+func (p *lruPolicy[K]) cost() int64 {
+	return p.total
+}
+
+// defaultCost is the cost of a value when Options.Cost is not set: the length of strings and byte slices,
+// and one for anything else, which makes MaxCost an item count.
+func defaultCost[V any](value V) int64 {
+	switch v := any(value).(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		return 1
+	}
+}
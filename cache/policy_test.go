@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxCostEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := NewWithOptions(Options[string, []byte]{
+		DisableJanitor: true,
+		MaxCost:        10,
+		OnEvict: func(key string, value []byte, reason EvictReason) {
+			if reason == ReasonEvicted {
+				evicted = append(evicted, key)
+			}
+		},
+	})
+	defer c.Close()
+
+	c.Set("a", make([]byte, 4))
+	c.Set("b", make([]byte, 4))
+	c.Get("a")
+	c.Set("c", make([]byte, 4))
+	if strings.Join(evicted, ",") != "b" {
+		t.Errorf("Expected b to be evicted as least recently used, got %v", evicted)
+	}
+	if _, found := c.Get("a"); !found {
+		t.Error("Expected a to be kept after its hit")
+	}
+	stats := c.Stats()
+	if stats.Cost != 8 || stats.Size != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected cost 8 of 2 items and 1 eviction, got %+v", stats)
+	}
+
+	c.Set("a", make([]byte, 1))
+	if cost := c.Stats().Cost; cost != 5 {
+		t.Errorf("Expected replacing a to update the cost to 5, got %d", cost)
+	}
+	c.Remove("c")
+	if cost := c.Stats().Cost; cost != 1 {
+		t.Errorf("Expected Remove to release the cost, got %d", cost)
+	}
+}
+
+func TestMaxCostOversizedValue(t *testing.T) {
+	c := NewWithOptions(Options[string, string]{DisableJanitor: true, MaxCost: 10})
+	defer c.Close()
+
+	c.Set("small", "abc")
+	c.Set("huge", strings.Repeat("x", 11))
+	if _, found := c.Get("huge"); found {
+		t.Error("Expected a value larger than MaxCost not to be kept")
+	}
+	if _, found := c.Get("small"); !found {
+		t.Error("Expected an oversized value not to evict the others")
+	}
+}
+
+func TestMaxCostCustomCost(t *testing.T) {
+	c := NewWithOptions(Options[int, int]{
+		DisableJanitor: true,
+		MaxCost:        100,
+		Cost:           func(value int) int64 { return int64(value) },
+	})
+	defer c.Close()
+
+	for i := 1; i <= 20; i++ {
+		c.Set(i, i)
+	}
+	if cost := c.Stats().Cost; cost > 100 {
+		t.Errorf("Expected the cost to stay under 100, got %d", cost)
+	}
+	if _, found := c.Get(20); !found {
+		t.Error("Expected the latest item to be kept")
+	}
+	c.Set(0, -5)
+	if cost := c.Stats().Cost; cost > 100 {
+		t.Errorf("Expected a negative cost to count as zero, got %d", cost)
+	}
+
+	c.Clear()
+	if stats := c.Stats(); stats.Cost != 0 || stats.Size != 0 {
+		t.Errorf("Expected Clear to reset the cost, got %+v", stats)
+	}
+}
+
+func TestDefaultCost(t *testing.T) {
+	if cost := defaultCost([]byte("abcd")); cost != 4 {
+		t.Errorf("Expected 4, got %d", cost)
+	}
+	if cost := defaultCost("ab"); cost != 2 {
+		t.Errorf("Expected 2, got %d", cost)
+	}
+	if cost := defaultCost(struct{}{}); cost != 1 {
+		t.Errorf("Expected 1, got %d", cost)
+	}
+}
@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisOptions configures a RedisCache.
+// Addr is the host:port of the server, Password and DB are sent with AUTH and SELECT on every new connection.
+// KeyPrefix is prepended to every encoded key, so several caches can share one database.
+// KeyCodec encodes keys, nil means the raw bytes for string keys and JSON for anything else.
+// ValueCodec encodes values, nil means GobCodec.
+// DialTimeout and IOTimeout bound connecting and each round trip, zero means five seconds.
+// PoolSize is the number of idle connections kept, zero means ten.
+// OnError is called with the errors the ICache methods cannot return, nil means they are logged.
+type RedisOptions[K comparable, V any] struct {
+	Addr        string
+	Password    string
+	DB          int
+	KeyPrefix   string
+	KeyCodec    Codec[K]
+	ValueCodec  Codec[V]
+	DialTimeout time.Duration
+	IOTimeout   time.Duration
+	PoolSize    int
+	OnError     func(err error)
+}
+
+// RedisCache is an ICache backed by a Redis server, so several processes share the same entries.
+type RedisCache[K comparable, V any] struct {
+	pool       *respPool
+	prefix     string
+	keyCodec   Codec[K]
+	valueCodec Codec[V]
+	onError    func(err error)
+}
+
+var _ ICache[string, any] = (*RedisCache[string, any])(nil)
+
+var defaultRedisTimeout = 5 * time.Second
+
+// NewRedis creates a RedisCache, connections are opened lazily on first use.
+func NewRedis[K comparable, V any](opts RedisOptions[K, V]) *RedisCache[K, V] {
+	dialTimeout := opts.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = defaultRedisTimeout
+	}
+	ioTimeout := opts.IOTimeout
+	if ioTimeout <= 0 {
+		ioTimeout = defaultRedisTimeout
+	}
+	poolSize := opts.PoolSize
+	if poolSize <= 0 {
+		poolSize = 10
+	}
+
+	var init [][][]byte
+	if opts.Password != "" {
+		init = append(init, [][]byte{[]byte("AUTH"), []byte(opts.Password)})
+	}
+	if opts.DB != 0 {
+		init = append(init, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(opts.DB))})
+	}
+
+	c := &RedisCache[K, V]{
+		pool: &respPool{
+			dial: func() (net.Conn, error) {
+				return net.DialTimeout("tcp", opts.Addr, dialTimeout)
+			},
+			init:      init,
+			ioTimeout: ioTimeout,
+			maxIdle:   poolSize,
+		},
+		prefix:     opts.KeyPrefix,
+		keyCodec:   opts.KeyCodec,
+		valueCodec: opts.ValueCodec,
+		onError:    opts.OnError,
+	}
+	if c.keyCodec == nil {
+		c.keyCodec = defaultKeyCodec[K]()
+	}
+	if c.valueCodec == nil {
+		c.valueCodec = GobCodec[V]{}
+	}
+	if c.onError == nil {
+		c.onError = func(err error) {
+			logger.Logger.Errorf("cache: redis %s failed with: %s", opts.Addr, err)
+		}
+	}
+	return c
+}
+
+// Set adds or updates a key-value pair in the cache with optional TTL, if no TTL is specified the item will not expire.
+// The TTL is sent with millisecond precision as PX.
+func (c *RedisCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
+	cmd, err := c.setCommand(key, value, ttl...)
+	if err == nil {
+		_, err = c.pool.do(cmd...)
+	}
+	if err != nil {
+		c.onError(err)
+	}
+}
+
+// Get retrieves the value associated with the given key.
+func (c *RedisCache[K, V]) Get(key K) (V, bool) {
+	var zeroV V
+	k, err := c.encodeKey(key)
+	if err != nil {
+		c.onError(err)
+		return zeroV, false
+	}
+	reply, err := c.pool.do([]byte("GET"), k)
+	if err != nil {
+		c.onError(err)
+		return zeroV, false
+	}
+	return c.decodeValue(reply)
+}
+
+// Remove deletes the key-value pair with the specified key.
+func (c *RedisCache[K, V]) Remove(key K) {
+	c.RemoveMany(key)
+}
+
+// Pop removes and returns the value associated with the specified key, GET and DEL run in one transaction.
+func (c *RedisCache[K, V]) Pop(key K) (V, bool) {
+	var zeroV V
+	k, err := c.encodeKey(key)
+	if err != nil {
+		c.onError(err)
+		return zeroV, false
+	}
+	replies, err := c.pool.pipeline(
+		[][]byte{[]byte("MULTI")},
+		[][]byte{[]byte("GET"), k},
+		[][]byte{[]byte("DEL"), k},
+		[][]byte{[]byte("EXEC")},
+	)
+	if err != nil {
+		c.onError(err)
+		return zeroV, false
+	}
+	results, ok := replies[3].([]any)
+	if !ok || len(results) != 2 {
+		c.onError(fmt.Errorf("cache: redis transaction failed: %v", replies[3]))
+		return zeroV, false
+	}
+	return c.decodeValue(results[0])
+}
+
+// SetMany adds or updates all key-value pairs with the same optional TTL in a single round trip.
+func (c *RedisCache[K, V]) SetMany(items map[K]V, ttl ...time.Duration) {
+	cmds := make([][][]byte, 0, len(items))
+	for key, value := range items {
+		cmd, err := c.setCommand(key, value, ttl...)
+		if err != nil {
+			c.onError(err)
+			return
+		}
+		cmds = append(cmds, cmd)
+	}
+	if len(cmds) == 0 {
+		return
+	}
+	replies, err := c.pool.pipeline(cmds...)
+	if err != nil {
+		c.onError(err)
+		return
+	}
+	for _, reply := range replies {
+		if respErr, ok := reply.(RespError); ok {
+			c.onError(respErr)
+		}
+	}
+}
+
+// GetMany returns the values of all given keys that are present, using a single MGET.
+func (c *RedisCache[K, V]) GetMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+	if len(keys) == 0 {
+		return values
+	}
+	cmd := make([][]byte, 0, len(keys)+1)
+	cmd = append(cmd, []byte("MGET"))
+	for _, key := range keys {
+		k, err := c.encodeKey(key)
+		if err != nil {
+			c.onError(err)
+			return values
+		}
+		cmd = append(cmd, k)
+	}
+	reply, err := c.pool.do(cmd...)
+	if err != nil {
+		c.onError(err)
+		return values
+	}
+	results, ok := reply.([]any)
+	if !ok || len(results) != len(keys) {
+		c.onError(fmt.Errorf("cache: unexpected MGET reply %v", reply))
+		return values
+	}
+	for i, result := range results {
+		if value, found := c.decodeValue(result); found {
+			values[keys[i]] = value
+		}
+	}
+	return values
+}
+
+// RemoveMany deletes all given keys with a single DEL.
+func (c *RedisCache[K, V]) RemoveMany(keys ...K) {
+	if len(keys) == 0 {
+		return
+	}
+	cmd := make([][]byte, 0, len(keys)+1)
+	cmd = append(cmd, []byte("DEL"))
+	for _, key := range keys {
+		k, err := c.encodeKey(key)
+		if err != nil {
+			c.onError(err)
+			return
+		}
+		cmd = append(cmd, k)
+	}
+	if _, err := c.pool.do(cmd...); err != nil {
+		c.onError(err)
+	}
+}
+
+// Ping checks the connection to the server.
+func (c *RedisCache[K, V]) Ping() error {
+	_, err := c.pool.do([]byte("PING"))
+	return err
+}
+
+// Close closes the idle connections, the cache must not be used afterwards.
+func (c *RedisCache[K, V]) Close() error {
+	return c.pool.close()
+}
+
+func (c *RedisCache[K, V]) setCommand(key K, value V, ttl ...time.Duration) ([][]byte, error) {
+	k, err := c.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.valueCodec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode value of key %v: %w", key, err)
+	}
+	cmd := [][]byte{[]byte("SET"), k, v}
+	if len(ttl) > 0 {
+		// PX rejects zero, a TTL under a millisecond is rounded up
+		ms := ttl[0].Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		cmd = append(cmd, []byte("PX"), []byte(strconv.FormatInt(ms, 10)))
+	}
+	return cmd, nil
+}
+
+func (c *RedisCache[K, V]) encodeKey(key K) ([]byte, error) {
+	k, err := c.keyCodec.Encode(key)
+	if err != nil {
+		return nil, fmt.Errorf("encode key %v: %w", key, err)
+	}
+	if c.prefix == "" {
+		return k, nil
+	}
+	return append([]byte(c.prefix), k...), nil
+}
+
+// decodeValue decodes a bulk string reply, a null reply is a miss.
+func (c *RedisCache[K, V]) decodeValue(reply any) (V, bool) {
+	var zeroV V
+	switch r := reply.(type) {
+	case []byte:
+		if r == nil {
+			return zeroV, false
+		}
+		value, err := c.valueCodec.Decode(r)
+		if err != nil {
+			c.onError(fmt.Errorf("decode value: %w", err))
+			return zeroV, false
+		}
+		return value, true
+	case RespError:
+		c.onError(r)
+	default:
+		c.onError(errors.New("cache: unexpected redis reply type"))
+	}
+	return zeroV, false
+}
+
+// stringCodec stores string keys as they are, so they stay readable in Redis.
+type stringCodec struct{}
+
+func (stringCodec) Encode(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (stringCodec) Decode(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// defaultKeyCodec returns the raw string codec for string keys and JSONCodec for any other key type.
+func defaultKeyCodec[K comparable]() Codec[K] {
+	if codec, ok := any(stringCodec{}).(Codec[K]); ok {
+		return codec
+	}
+	return JSONCodec[K]{}
+}
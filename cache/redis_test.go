@@ -0,0 +1,329 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process server speaking enough RESP2 to test RedisCache.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+	mu       sync.Mutex
+	data     map[string]fakeEntry
+	commands []string
+}
+
+type fakeEntry struct {
+	value  []byte
+	expiry time.Time
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+	s := &fakeRedis{listener: listener, password: password, data: make(map[string]fakeEntry)}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(newRespConn(conn))
+	}
+}
+
+func (s *fakeRedis) handle(conn *respConn) {
+	defer conn.conn.Close()
+	authed := s.password == ""
+	var queue [][][]byte
+	inMulti := false
+	for {
+		reply, err := conn.readReply()
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([][]byte, len(items))
+		for i, item := range items {
+			args[i], _ = item.([]byte)
+		}
+		if len(args) == 0 {
+			return
+		}
+		name := strings.ToUpper(string(args[0]))
+		s.mu.Lock()
+		s.commands = append(s.commands, name)
+		s.mu.Unlock()
+
+		var out string
+		switch {
+		case name == "AUTH":
+			authed = string(args[1]) == s.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		case name == "MULTI":
+			inMulti = true
+			out = "+OK\r\n"
+		case name == "EXEC":
+			inMulti = false
+			out = "*" + strconv.Itoa(len(queue)) + "\r\n"
+			for _, cmd := range queue {
+				out += s.exec(cmd)
+			}
+			queue = nil
+		case inMulti:
+			queue = append(queue, args)
+			out = "+QUEUED\r\n"
+		default:
+			out = s.exec(args)
+		}
+		conn.w.WriteString(out)
+		if conn.r.Buffered() == 0 {
+			conn.flush()
+		}
+	}
+}
+
+// exec runs one command and returns the encoded reply.
+func (s *fakeRedis) exec(args [][]byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bulk := func(key string) string {
+		entry, found := s.data[key]
+		if !found || (!entry.expiry.IsZero() && entry.expiry.Before(time.Now())) {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(entry.value)) + "\r\n" + string(entry.value) + "\r\n"
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		return bulk(string(args[1]))
+	case "SET":
+		entry := fakeEntry{value: append([]byte(nil), args[2]...)}
+		if len(args) == 5 && strings.ToUpper(string(args[3])) == "PX" {
+			ms, err := strconv.Atoi(string(args[4]))
+			if err != nil || ms <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			entry.expiry = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		s.data[string(args[1])] = entry
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, found := s.data[string(key)]; found {
+				delete(s.data, string(key))
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "MGET":
+		out := "*" + strconv.Itoa(len(args)-1) + "\r\n"
+		for _, key := range args[1:] {
+			out += bulk(string(key))
+		}
+		return out
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func (s *fakeRedis) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.data[key]
+	return found
+}
+
+func (s *fakeRedis) commandCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, cmd := range s.commands {
+		if cmd == name {
+			n++
+		}
+	}
+	return n
+}
+
+type redisUser struct {
+	Name string
+	Age  int
+}
+
+func TestRedisCache(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c := NewRedis(RedisOptions[string, redisUser]{Addr: server.addr(), KeyPrefix: "users:"})
+	defer c.Close()
+
+	if err := c.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	c.Set("alice", redisUser{Name: "Alice", Age: 30})
+	if value, found := c.Get("alice"); !found || value.Name != "Alice" || value.Age != 30 {
+		t.Errorf("Expected Alice, got %+v", value)
+	}
+	if !server.has("users:alice") {
+		t.Error("Expected the key to be stored with its prefix")
+	}
+	if _, found := c.Get("bob"); found {
+		t.Error("Expected a miss for a missing key")
+	}
+
+	if value, found := c.Pop("alice"); !found || value.Name != "Alice" {
+		t.Errorf("Expected to pop Alice, got %+v", value)
+	}
+	if _, found := c.Get("alice"); found {
+		t.Error("Expected Pop to delete the key")
+	}
+
+	c.Set("carol", redisUser{Name: "Carol"})
+	c.Remove("carol")
+	if _, found := c.Get("carol"); found {
+		t.Error("Expected Remove to delete the key")
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c := NewRedis(RedisOptions[int, string]{Addr: server.addr()})
+	defer c.Close()
+
+	c.Set(1, "short", 20*time.Millisecond)
+	if _, found := c.Get(1); !found {
+		t.Fatal("Expected the key before its TTL")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, found := c.Get(1); found {
+		t.Error("Expected the key to expire")
+	}
+	// Non-string keys are JSON encoded
+	if !server.has("1") {
+		t.Error("Expected the int key to be stored as JSON")
+	}
+}
+
+func TestRedisCacheBulk(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c := NewRedis(RedisOptions[string, int]{Addr: server.addr(), ValueCodec: JSONCodec[int]{}})
+	defer c.Close()
+
+	c.SetMany(map[string]int{"a": 1, "b": 2, "c": 3}, time.Minute)
+	values := c.GetMany("a", "b", "missing")
+	if len(values) != 2 || values["a"] != 1 || values["b"] != 2 {
+		t.Errorf("Expected a and b, got %v", values)
+	}
+	c.RemoveMany("a", "b")
+	if values = c.GetMany("a", "b", "c"); len(values) != 1 || values["c"] != 3 {
+		t.Errorf("Expected only c to be left, got %v", values)
+	}
+	if n := server.commandCount("MGET"); n != 2 {
+		t.Errorf("Expected one MGET per GetMany, got %d", n)
+	}
+}
+
+func TestRedisCacheAuthAndErrors(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	var errs []error
+	var mu sync.Mutex
+	onError := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	wrong := NewRedis(RedisOptions[string, string]{Addr: server.addr(), Password: "wrong", OnError: onError})
+	defer wrong.Close()
+	wrong.Set("key", "value")
+	var respErr RespError
+	if len(errs) != 1 || !errors.As(errs[0], &respErr) {
+		t.Fatalf("Expected a RESP error for a wrong password, got %v", errs)
+	}
+
+	right := NewRedis(RedisOptions[string, string]{Addr: server.addr(), Password: "secret", DB: 1, OnError: onError})
+	defer right.Close()
+	right.Set("key", "value")
+	if value, found := right.Get("key"); !found || value != "value" {
+		t.Errorf("Expected value, got %v", value)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected no more errors, got %v", errs)
+	}
+
+	server.listener.Close()
+	down := NewRedis(RedisOptions[string, string]{Addr: server.addr(), OnError: onError, DialTimeout: 100 * time.Millisecond})
+	defer down.Close()
+	if _, found := down.Get("key"); found {
+		t.Error("Expected a miss when the server is down")
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected the dial error to be reported, got %v", errs)
+	}
+}
+
+func TestRespReplies(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write([]byte("+OK\r\n-ERR bad\r\n:42\r\n$5\r\nhello\r\n$-1\r\n*2\r\n$1\r\na\r\n:1\r\n*-1\r\n"))
+		server.Close()
+	}()
+
+	conn := newRespConn(client)
+	want := []string{"OK", "ERR bad", "42", "hello", "[]", "[a 1]", "[]"}
+	for i, w := range want {
+		reply, err := conn.readReply()
+		if err != nil {
+			t.Fatalf("Reply %d: %v", i, err)
+		}
+		var got string
+		switch r := reply.(type) {
+		case []byte:
+			got = string(r)
+			if r == nil {
+				got = "[]"
+			}
+		case []any:
+			got = "[]"
+			if r != nil {
+				got = fmt.Sprintf("[%s %v]", r[0], r[1])
+			}
+		default:
+			got = fmt.Sprint(r)
+		}
+		if got != w {
+			t.Errorf("Reply %d: expected %q, got %q", i, w, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingLoader returns the number of times it has been called as the value.
+type countingLoader struct {
+	calls   atomic.Int32
+	release chan struct{}
+	fail    atomic.Bool
+}
+
+func (l *countingLoader) load(ctx context.Context, key string) (int, error) {
+	n := l.calls.Add(1)
+	if l.release != nil {
+		<-l.release
+	}
+	if l.fail.Load() {
+		return 0, errors.New("upstream down")
+	}
+	return int(n), nil
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	loader := &countingLoader{}
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		Loader:         loader.load,
+		RefreshAhead:   10 * time.Second,
+	})
+	defer c.Close()
+
+	c.Set("key", 0, time.Minute)
+	clock.Advance(40 * time.Second)
+	c.Get("key")
+	if loader.calls.Load() != 0 {
+		t.Fatal("Expected no refresh outside the refresh-ahead window")
+	}
+
+	clock.Advance(15 * time.Second)
+	if value, found := c.Get("key"); !found || value != 0 {
+		t.Fatalf("Expected the current value while refreshing, got %v", value)
+	}
+	waitFor(t, func() bool {
+		value, _ := c.Get("key")
+		return value == 1
+	})
+	if ttl, _ := c.TTL("key"); ttl != time.Minute {
+		t.Errorf("Expected the refreshed item to get a full TTL, got %v", ttl)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	loader := &countingLoader{release: make(chan struct{})}
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		Loader:         loader.load,
+		StaleGrace:     30 * time.Second,
+	})
+	defer c.Close()
+
+	c.Set("key", 0, time.Minute)
+	clock.Advance(70 * time.Second)
+
+	// Expired but within the grace period, the stale value is served and only one refresh starts
+	for i := 0; i < 10; i++ {
+		if value, found := c.Get("key"); !found || value != 0 {
+			t.Fatalf("Expected stale value, got %v (found %v)", value, found)
+		}
+	}
+	// The janitor must keep the stale item
+	c.DeleteExpired()
+	if _, found := c.Get("key"); !found {
+		t.Fatal("Expected stale item to survive DeleteExpired")
+	}
+	close(loader.release)
+	waitFor(t, func() bool {
+		value, _ := c.Get("key")
+		return value == 1
+	})
+	if calls := loader.calls.Load(); calls != 1 {
+		t.Errorf("Expected a single refresh, got %d", calls)
+	}
+	if stats := c.Stats(); stats.StaleHits == 0 {
+		t.Errorf("Expected stale hits to be counted, got %+v", stats)
+	}
+
+	// Beyond the grace period the item is gone
+	clock.Advance(2 * time.Minute)
+	if _, found := c.Get("key"); found {
+		t.Error("Expected a miss after the grace period")
+	}
+	c.DeleteExpired()
+	if c.Stats().Size != 0 {
+		t.Error("Expected the janitor to purge the item after the grace period")
+	}
+}
+
+func TestRefreshFailureKeepsStaleValue(t *testing.T) {
+	clock := newFakeClock()
+	loader := &countingLoader{}
+	loader.fail.Store(true)
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		Loader:         loader.load,
+		StaleGrace:     time.Minute,
+	})
+	defer c.Close()
+
+	c.Set("key", 7, time.Second)
+	clock.Advance(2 * time.Second)
+	c.Get("key")
+	waitFor(t, func() bool { return c.Stats().LoadErrors == 1 })
+	if value, found := c.Get("key"); !found || value != 7 {
+		t.Errorf("Expected stale value to be kept after a failed refresh, got %v", value)
+	}
+}
+
+func TestGetOrLoadDefaultLoader(t *testing.T) {
+	loader := &countingLoader{}
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Loader: loader.load})
+	defer c.Close()
+
+	if value, err := c.GetOrLoad(context.Background(), "key", nil); err != nil || value != 1 {
+		t.Errorf("Expected the cache loader to be used, got %v (err: %v)", value, err)
+	}
+
+	noLoader := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer noLoader.Close()
+	if _, err := noLoader.GetOrLoad(context.Background(), "key", nil); err == nil {
+		t.Error("Expected an error without any loader")
+	}
+}
+
+func TestRefreshDoesNotAddUpJitter(t *testing.T) {
+	clock := newFakeClock()
+	loader := &countingLoader{}
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		Loader:         loader.load,
+		TTLJitter:      time.Hour,
+		RefreshAhead:   2 * time.Hour,
+	})
+	defer c.Close()
+
+	c.Set("key", 0, time.Minute)
+	for i := 1; i <= 5; i++ {
+		c.Get("key")
+		waitFor(t, func() bool {
+			value, _ := c.Get("key")
+			return value >= i
+		})
+		if ttl, _ := c.TTL("key"); ttl < time.Minute || ttl >= time.Minute+time.Hour {
+			t.Fatalf("Expected refresh %d to jitter the 1m TTL once, got %v", i, ttl)
+		}
+	}
+}
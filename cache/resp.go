@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RespError is an error reply sent by a RESP server, e.g. "ERR unknown command".
+type RespError string
+
+func (e RespError) Error() string {
+	return string(e)
+}
+
+var errPoolClosed = errors.New("cache: redis pool closed")
+
+// respConn is a single connection speaking RESP2, the Redis serialization protocol.
+// Replies are decoded as string (simple string), int64 (integer), []byte (bulk string, nil for a null bulk string),
+// []any (array, nil for a null array) or RespError.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func newRespConn(conn net.Conn) *respConn {
+	return &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+}
+
+// writeCommand buffers a command as an array of bulk strings, call flush to send it.
+func (c *respConn) writeCommand(args ...[]byte) error {
+	c.w.WriteByte('*')
+	c.w.WriteString(strconv.Itoa(len(args)))
+	c.w.WriteString("\r\n")
+	for _, arg := range args {
+		c.w.WriteByte('$')
+		c.w.WriteString(strconv.Itoa(len(arg)))
+		c.w.WriteString("\r\n")
+		c.w.Write(arg)
+		if _, err := c.w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *respConn) flush() error {
+	return c.w.Flush()
+}
+
+// readReply reads one reply, a RespError reply is returned as the value, not as the error.
+func (c *respConn) readReply() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("cache: empty RESP line")
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return RespError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("cache: bad RESP bulk length %q", line)
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("cache: bad RESP array length %q", line)
+		}
+		if n < 0 {
+			return []any(nil), nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("cache: unknown RESP type %q", line[0])
+	}
+}
+
+// readLine reads a CRLF terminated line without the CRLF.
+func (c *respConn) readLine() ([]byte, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("cache: malformed RESP line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// respPool is a pool of RESP connections to one server.
+type respPool struct {
+	dial      func() (net.Conn, error)
+	init      [][][]byte // commands sent on every new connection, e.g. AUTH and SELECT
+	ioTimeout time.Duration
+	mu        sync.Mutex
+	idle      []*respConn
+	maxIdle   int
+	closed    bool
+}
+
+// pipeline sends all commands in one round trip and returns their replies in order.
+// A RespError reply is returned as a value, only connection failures are returned as error.
+func (p *respPool) pipeline(cmds ...[][]byte) ([]any, error) {
+	conn, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	replies, err := p.roundTrip(conn, cmds...)
+	if err != nil {
+		// The connection state is unknown after an I/O error
+		conn.conn.Close()
+		return nil, err
+	}
+	p.put(conn)
+	return replies, nil
+}
+
+// do sends a single command and returns its reply, an error reply is returned as error.
+func (p *respPool) do(args ...[]byte) (any, error) {
+	replies, err := p.pipeline(args)
+	if err != nil {
+		return nil, err
+	}
+	if respErr, ok := replies[0].(RespError); ok {
+		return nil, respErr
+	}
+	return replies[0], nil
+}
+
+func (p *respPool) roundTrip(conn *respConn, cmds ...[][]byte) ([]any, error) {
+	if p.ioTimeout > 0 {
+		conn.conn.SetDeadline(time.Now().Add(p.ioTimeout))
+	}
+	for _, cmd := range cmds {
+		if err := conn.writeCommand(cmd...); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]any, len(cmds))
+	for i := range replies {
+		reply, err := conn.readReply()
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func (p *respPool) get() (*respConn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	netConn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn := newRespConn(netConn)
+	if len(p.init) > 0 {
+		replies, err := p.roundTrip(conn, p.init...)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		for _, reply := range replies {
+			if respErr, ok := reply.(RespError); ok {
+				netConn.Close()
+				return nil, respErr
+			}
+		}
+	}
+	return conn, nil
+}
+
+func (p *respPool) put(conn *respConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle) >= p.maxIdle {
+		conn.conn.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+func (p *respPool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	var err error
+	for _, conn := range p.idle {
+		if closeErr := conn.conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	p.idle = nil
+	return err
+}
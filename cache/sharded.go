@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ShardedCache spreads keys over several TTLCache shards by hash, so writers on different shards
+// never contend on the same lock. Each shard runs its own janitor.
+type ShardedCache[K comparable, V any] struct {
+	shards        []*TTLCache[K, V]
+	seed          maphash.Seed
+	mask          uint64
+	onStats       func(stats Stats)
+	statsInterval time.Duration
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	closeOnce     sync.Once
+}
+
+var _ ICache[string, any] = (*ShardedCache[string, any])(nil)
+
+// NewSharded creates a ShardedCache with the given number of shards, rounded up to a power of two.
+// A shardCount of zero or less means four shards per CPU. Every shard is configured by opts,
+// except OnStats which is called once per interval with the stats of all shards combined,
+// and SnapshotPath which is not supported for sharded caches. MaxCost applies to each shard on its own.
+func NewSharded[K comparable, V any](shardCount int, opts Options[K, V]) *ShardedCache[K, V] {
+	if shardCount <= 0 {
+		shardCount = 4 * runtime.GOMAXPROCS(0)
+	}
+	n := 1
+	for n < shardCount {
+		n <<= 1
+	}
+
+	c := &ShardedCache[K, V]{
+		shards:        make([]*TTLCache[K, V], n),
+		seed:          maphash.MakeSeed(),
+		mask:          uint64(n - 1),
+		onStats:       opts.OnStats,
+		statsInterval: opts.StatsInterval,
+		stopCh:        make(chan struct{}),
+	}
+	if c.statsInterval <= 0 {
+		c.statsInterval = defaultStatsInterval
+	}
+
+	shardOpts := opts
+	shardOpts.OnStats = nil
+	shardOpts.SnapshotPath = ""
+	for i := range c.shards {
+		c.shards[i] = NewWithOptions(shardOpts)
+	}
+
+	if c.onStats != nil {
+		ctx := opts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		c.doneCh = make(chan struct{})
+		go c.exportStats(ctx)
+	}
+	return c
+}
+
+// shard returns the shard owning key.
+func (c *ShardedCache[K, V]) shard(key K) *TTLCache[K, V] {
+	return c.shards[hashKey(c.seed, key)&c.mask]
+}
+
+// Set adds or updates a key-value pair in the cache with optional TTL, if no TTL is specified the item will not expire.
+func (c *ShardedCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
+	c.shard(key).Set(key, value, ttl...)
+}
+
+// Get retrieves the value associated with the given key.
+func (c *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Remove deletes the key-value pair with the specified key.
+func (c *ShardedCache[K, V]) Remove(key K) {
+	c.shard(key).Remove(key)
+}
+
+// Pop removes and returns the value associated with the specified key.
+func (c *ShardedCache[K, V]) Pop(key K) (V, bool) {
+	return c.shard(key).Pop(key)
+}
+
+// GetOrLoad returns the cached value of key, on a miss it calls loader and caches its result, see TTLCache.GetOrLoad.
+func (c *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V], opts ...LoadOptions) (V, error) {
+	return c.shard(key).GetOrLoad(ctx, key, loader, opts...)
+}
+
+// DeleteExpired removes all expired items from every shard.
+func (c *ShardedCache[K, V]) DeleteExpired() {
+	for _, shard := range c.shards {
+		shard.DeleteExpired()
+	}
+}
+
+// Stats returns the statistics of all shards combined.
+func (c *ShardedCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range c.shards {
+		s := shard.Stats()
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.StaleHits += s.StaleHits
+		total.Sets += s.Sets
+		total.Expirations += s.Expirations
+		total.Removals += s.Removals
+		total.Evictions += s.Evictions
+		total.Replacements += s.Replacements
+		total.Size += s.Size
+		total.Cost += s.Cost
+		total.Loads += s.Loads
+		total.LoadErrors += s.LoadErrors
+		total.LoadTime += s.LoadTime
+	}
+	return total
+}
+
+// Close stops the janitors of all shards and the stats hook. It is idempotent and always returns nil.
+func (c *ShardedCache[K, V]) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stopCh)
+	})
+	if c.doneCh != nil {
+		<-c.doneCh
+	}
+	for _, shard := range c.shards {
+		shard.Close()
+	}
+	return nil
+}
+
+func (c *ShardedCache[K, V]) exportStats(ctx context.Context) {
+	defer close(c.doneCh)
+	ticker := time.NewTicker(c.statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.onStats(c.Stats())
+		case <-c.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
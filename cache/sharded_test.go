@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedSetAndGet(t *testing.T) {
+	c := NewSharded(0, Options[string, int]{})
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		c.Set("key"+strconv.Itoa(i), i)
+	}
+	for i := 0; i < 1000; i++ {
+		if value, found := c.Get("key" + strconv.Itoa(i)); !found || value != i {
+			t.Fatalf("Expected %d, got %v", i, value)
+		}
+	}
+	if value, found := c.Pop("key1"); !found || value != 1 {
+		t.Errorf("Expected to pop 1, got %v", value)
+	}
+	c.Remove("key2")
+	if _, found := c.Get("key2"); found {
+		t.Error("Expected key2 to be removed")
+	}
+	if size := c.Stats().Size; size != 998 {
+		t.Errorf("Expected 998 items, got %d", size)
+	}
+}
+
+func TestShardedPointerKeys(t *testing.T) {
+	type user struct{ name string }
+	c := NewSharded(16, Options[*user, int]{DisableJanitor: true})
+	defer c.Close()
+
+	keys := make([]*user, 100)
+	for i := range keys {
+		keys[i] = &user{name: strconv.Itoa(i)}
+		c.Set(keys[i], i)
+	}
+	// Pointer keys are compared by address, so changing what they point to must not move them
+	for i, key := range keys {
+		key.name = "renamed"
+		if value, found := c.Get(key); !found || value != i {
+			t.Fatalf("Expected %d to be found, got %v, %v", i, value, found)
+		}
+		c.Remove(key)
+	}
+	if size := c.Stats().Size; size != 0 {
+		t.Errorf("Expected every key to be removed, got %d items", size)
+	}
+}
+
+func TestShardedShardCount(t *testing.T) {
+	c := NewSharded(5, Options[int, int]{DisableJanitor: true})
+	defer c.Close()
+
+	if len(c.shards) != 8 {
+		t.Fatalf("Expected shard count rounded up to 8, got %d", len(c.shards))
+	}
+	used := make(map[*TTLCache[int, int]]bool)
+	for i := 0; i < 1000; i++ {
+		used[c.shard(i)] = true
+	}
+	if len(used) != len(c.shards) {
+		t.Errorf("Expected keys to spread over all %d shards, got %d", len(c.shards), len(used))
+	}
+}
+
+func TestShardedTTLAndLoad(t *testing.T) {
+	c := NewSharded(4, Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	c.Set("short", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	c.DeleteExpired()
+	if stats := c.Stats(); stats.Expirations != 1 || stats.Size != 0 {
+		t.Errorf("Expected the expired item to be purged, got %+v", stats)
+	}
+
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetOrLoad(context.Background(), "loaded", func(ctx context.Context, key string) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+				return 1, nil
+			})
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("Expected one load, got %d", calls)
+	}
+}
+
+// The parallel benchmarks compare the single-lock TTLCache with ShardedCache, run them with -cpu 1,4,16,64 to see the scaling.
+
+func BenchmarkParallelGetTTLCache(b *testing.B) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	benchmarkParallelGet(b, c)
+}
+
+func BenchmarkParallelGetSharded(b *testing.B) {
+	c := NewSharded(0, Options[string, int]{DisableJanitor: true})
+	benchmarkParallelGet(b, c)
+}
+
+func BenchmarkParallelSetTTLCache(b *testing.B) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	benchmarkParallelSet(b, c)
+}
+
+func BenchmarkParallelSetSharded(b *testing.B) {
+	c := NewSharded(0, Options[string, int]{DisableJanitor: true})
+	benchmarkParallelSet(b, c)
+}
+
+func BenchmarkParallelMixedTTLCache(b *testing.B) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	benchmarkParallelMixed(b, c)
+}
+
+func BenchmarkParallelMixedSharded(b *testing.B) {
+	c := NewSharded(0, Options[string, int]{DisableJanitor: true})
+	benchmarkParallelMixed(b, c)
+}
+
+const benchKeys = 1 << 14
+
+func benchmarkKeys() []string {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func benchmarkParallelGet(b *testing.B, c ICache[string, int]) {
+	defer c.Close()
+	keys := benchmarkKeys()
+	for i, key := range keys {
+		c.Set(key, i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(keys[i&(benchKeys-1)])
+			i++
+		}
+	})
+}
+
+func benchmarkParallelSet(b *testing.B, c ICache[string, int]) {
+	defer c.Close()
+	keys := benchmarkKeys()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(keys[i&(benchKeys-1)], i)
+			i++
+		}
+	})
+}
+
+// benchmarkParallelMixed runs one write for every nine reads.
+func benchmarkParallelMixed(b *testing.B, c ICache[string, int]) {
+	defer c.Close()
+	keys := benchmarkKeys()
+	for i, key := range keys {
+		c.Set(key, i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i&(benchKeys-1)]
+			if i%10 == 0 {
+				c.Set(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Codec encodes and decodes keys or values of type T for snapshots.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// GobCodec encodes with encoding/gob, it is the default codec.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// JSONCodec encodes with encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+const snapshotVersion = 1
+
+type snapshotHeader struct {
+	Version int
+}
+
+// snapshotEntry is one item of a snapshot, Expiry is in unix nanoseconds, zero means no expiry.
+type snapshotEntry struct {
+	Key    []byte
+	Value  []byte
+	Expiry int64
+	Tags   []string
+}
+
+// SaveTo writes all unexpired items to w. Expiries are stored as absolute times,
+// so items keep their remaining TTL and time spent on disk counts against it.
+func (c *TTLCache[K, V]) SaveTo(w io.Writer) error {
+	// Encode without the lock, codecs may be slow
+	items := c.snapshot()
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion}); err != nil {
+		return err
+	}
+	for _, it := range items {
+		key, err := c.keyCodec.Encode(it.key)
+		if err != nil {
+			return fmt.Errorf("encode key %v: %w", it.key, err)
+		}
+		value, err := c.valueCodec.Encode(it.value)
+		if err != nil {
+			return fmt.Errorf("encode value of key %v: %w", it.key, err)
+		}
+		entry := snapshotEntry{Key: key, Value: value, Tags: it.tags}
+		if !it.expiry.IsZero() {
+			entry.Expiry = it.expiry.UnixNano()
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFrom adds the items of a snapshot written by SaveTo to the cache, items that expired in the meantime are skipped.
+func (c *TTLCache[K, V]) LoadFrom(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("decode snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	for {
+		var entry snapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("decode snapshot entry: %w", err)
+		}
+		key, err := c.keyCodec.Decode(entry.Key)
+		if err != nil {
+			return fmt.Errorf("decode key: %w", err)
+		}
+		value, err := c.valueCodec.Decode(entry.Value)
+		if err != nil {
+			return fmt.Errorf("decode value of key %v: %w", key, err)
+		}
+		if entry.Expiry == 0 {
+			c.SetWithTags(key, value, entry.Tags)
+			continue
+		}
+		if ttl := time.Unix(0, entry.Expiry).Sub(c.clock.Now()); ttl > 0 {
+			c.SetWithTags(key, value, entry.Tags, ttl)
+		}
+	}
+}
+
+// SaveFile writes a snapshot to path. It writes to a temporary file first, so a crash never leaves a truncated snapshot.
+func (c *TTLCache[K, V]) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = c.SaveTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads a snapshot written by SaveFile, a missing file is not an error.
+func (c *TTLCache[K, V]) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return c.LoadFrom(f)
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type snapshotValue struct {
+	Name  string
+	Count int
+}
+
+func TestSaveToAndLoadFrom(t *testing.T) {
+	clock := newFakeClock()
+	src := NewWithOptions(Options[string, snapshotValue]{DisableJanitor: true, Clock: clock})
+	defer src.Close()
+
+	src.Set("forever", snapshotValue{Name: "a", Count: 1})
+	src.Set("minute", snapshotValue{Name: "b", Count: 2}, time.Minute)
+	src.Set("expired", snapshotValue{Name: "c", Count: 3}, time.Second)
+	clock.Advance(2 * time.Second)
+
+	var buf bytes.Buffer
+	if err := src.SaveTo(&buf); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	clock.Advance(10 * time.Second)
+	dst := NewWithOptions(Options[string, snapshotValue]{DisableJanitor: true, Clock: clock})
+	defer dst.Close()
+	if err := dst.LoadFrom(&buf); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	if value, found := dst.Get("forever"); !found || value.Name != "a" || value.Count != 1 {
+		t.Errorf("Expected forever to be restored, got %+v", value)
+	}
+	if _, found := dst.Get("expired"); found {
+		t.Error("Expected expired item not to be restored")
+	}
+	// The minute TTL was set 12s ago, so 48s remain
+	clock.Advance(47 * time.Second)
+	if _, found := dst.Get("minute"); !found {
+		t.Error("Expected minute to be alive before its remaining TTL")
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := dst.Get("minute"); found {
+		t.Error("Expected minute to keep its original expiry")
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	opts := Options[int, snapshotValue]{
+		DisableJanitor: true,
+		KeyCodec:       JSONCodec[int]{},
+		ValueCodec:     JSONCodec[snapshotValue]{},
+	}
+	src := NewWithOptions(opts)
+	defer src.Close()
+	src.Set(1, snapshotValue{Name: "json"})
+
+	var buf bytes.Buffer
+	if err := src.SaveTo(&buf); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	dst := NewWithOptions(opts)
+	defer dst.Close()
+	if err := dst.LoadFrom(&buf); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if value, found := dst.Get(1); !found || value.Name != "json" {
+		t.Errorf("Expected value to round-trip through JSON, got %+v", value)
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	opts := Options[string, int]{DisableJanitor: true, SnapshotPath: path}
+
+	// A missing snapshot file means a cold start
+	c := NewWithOptions(opts)
+	c.Set("warm", 1)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed to save snapshot: %v", err)
+	}
+
+	restarted := NewWithOptions(opts)
+	defer restarted.Close()
+	if value, found := restarted.Get("warm"); !found || value != 1 {
+		t.Errorf("Expected warm start from snapshot, got %v", value)
+	}
+}
+
+func TestSnapshotInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, SnapshotPath: path, SnapshotInterval: time.Millisecond})
+	defer c.Close()
+	c.Set("key", 1)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		probe := NewWithOptions(Options[string, int]{DisableJanitor: true})
+		probe.LoadFile(path)
+		if _, found := probe.Get("key"); found {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected the periodic snapshot to contain the key")
+}
+
+func TestLoadFromRejectsGarbage(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+	if err := c.LoadFrom(bytes.NewBufferString("not a snapshot")); err == nil {
+		t.Error("Expected an error for a corrupt snapshot")
+	}
+}
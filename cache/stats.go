@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of the cache counters.
+// Hits and Misses count Get lookups, an expired item counts as a miss.
+// StaleHits counts expired items served during their stale grace period.
+// Sets counts writes, Expirations, Removals, Evictions and Replacements count items leaving the cache by reason.
+// Size is the number of items currently held, including expired ones not yet purged.
+// Cost is the total cost of those items, it is only tracked when the cache has a MaxCost.
+// Loads, LoadErrors and LoadTime describe the loader calls made by GetOrLoad and background refreshes.
+type Stats struct {
+	Hits         uint64
+	Misses       uint64
+	StaleHits    uint64
+	Sets         uint64
+	Expirations  uint64
+	Removals     uint64
+	Evictions    uint64
+	Replacements uint64
+	Size         int
+	Cost         int64
+	Loads        uint64
+	LoadErrors   uint64
+	LoadTime     time.Duration
+}
+
+// HitRatio returns the fraction of lookups that were hits, or zero if there were no lookups.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// AvgLoadTime returns the mean duration of a loader call, or zero if nothing was loaded.
+func (s Stats) AvgLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.LoadTime / time.Duration(s.Loads)
+}
+
+// counters holds the live statistics of a cache, all fields are updated atomically.
+type counters struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	staleHits  atomic.Uint64
+	sets       atomic.Uint64
+	evictions  [ReasonReplaced + 1]atomic.Uint64 // indexed by EvictReason
+	loads      atomic.Uint64
+	loadErrors atomic.Uint64
+	loadNanos  atomic.Int64
+}
+
+func (s *counters) evicted(reason EvictReason) {
+	if reason >= 0 && int(reason) < len(s.evictions) {
+		s.evictions[reason].Add(1)
+	}
+}
+
+func (s *counters) loaded(start time.Time, err error) {
+	s.loads.Add(1)
+	s.loadNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		s.loadErrors.Add(1)
+	}
+}
+
+func (s *counters) snapshot() Stats {
+	return Stats{
+		Hits:         s.hits.Load(),
+		Misses:       s.misses.Load(),
+		StaleHits:    s.staleHits.Load(),
+		Sets:         s.sets.Load(),
+		Expirations:  s.evictions[ReasonExpired].Load(),
+		Removals:     s.evictions[ReasonRemoved].Load(),
+		Evictions:    s.evictions[ReasonEvicted].Load(),
+		Replacements: s.evictions[ReasonReplaced].Load(),
+		Loads:        s.loads.Load(),
+		LoadErrors:   s.loadErrors.Load(),
+		LoadTime:     time.Duration(s.loadNanos.Load()),
+	}
+}
+
+// Stats returns a snapshot of the cache statistics.
+func (c *TTLCache[K, V]) Stats() Stats {
+	stats := c.stats.snapshot()
+	c.mu.RLock()
+	stats.Size = len(c.items)
+	if c.policy != nil {
+		stats.Cost = c.policy.cost()
+	}
+	c.mu.RUnlock()
+	return stats
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("a", 2)
+	c.Set("b", 1)
+	c.Set("expired", 1, time.Millisecond)
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+	c.Remove("b")
+	time.Sleep(5 * time.Millisecond)
+	c.DeleteExpired()
+
+	stats := c.Stats()
+	want := Stats{Hits: 2, Misses: 1, Sets: 4, Expirations: 1, Removals: 1, Replacements: 1, Size: 1}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	if ratio := stats.HitRatio(); ratio < 0.66 || ratio > 0.67 {
+		t.Errorf("Expected hit ratio 2/3, got %v", ratio)
+	}
+}
+
+func TestStatsLoads(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	c.GetOrLoad(context.Background(), "ok", func(ctx context.Context, key string) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return 1, nil
+	})
+	c.GetOrLoad(context.Background(), "fail", func(ctx context.Context, key string) (int, error) {
+		return 0, errors.New("fail")
+	})
+
+	stats := c.Stats()
+	if stats.Loads != 2 || stats.LoadErrors != 1 {
+		t.Errorf("Expected 2 loads and 1 error, got %d and %d", stats.Loads, stats.LoadErrors)
+	}
+	if stats.Misses != 2 || stats.Hits != 0 {
+		t.Errorf("Expected 2 misses and 0 hits, got %d and %d", stats.Misses, stats.Hits)
+	}
+	if stats.LoadTime < 5*time.Millisecond || stats.AvgLoadTime() <= 0 {
+		t.Errorf("Expected load time to be recorded, got %v", stats.LoadTime)
+	}
+}
+
+func TestOnStats(t *testing.T) {
+	exported := make(chan Stats, 1)
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		StatsInterval:  time.Millisecond,
+		OnStats: func(stats Stats) {
+			select {
+			case exported <- stats:
+			default:
+			}
+		},
+	})
+	defer c.Close()
+	c.Set("a", 1)
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case stats := <-exported:
+			if stats.Sets == 1 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected OnStats to export the set")
+		}
+	}
+}
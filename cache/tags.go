@@ -0,0 +1,80 @@
+package cache
+
+import "time"
+
+// SetWithTags is Set for an item associated with tags, so it can be dropped together with
+// every other item sharing one of them by InvalidateTag. Setting the key again replaces its tags.
+func (c *TTLCache[K, V]) SetWithTags(key K, value V, tags []string, ttl ...time.Duration) {
+	c.mu.Lock()
+	now := c.clock.Now()
+	it := c.newItem(key, value, now, ttl...)
+	it.tags = append([]string(nil), tags...)
+	evicted := c.insertLocked(it, now)
+	c.mu.Unlock()
+
+	c.notifySet(key, value)
+	c.notify(evicted...)
+}
+
+// InvalidateTag removes every item tagged with tag and returns how many were removed.
+func (c *TTLCache[K, V]) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	now := c.clock.Now()
+	keys := c.tags[tag]
+	evicted := make([]eviction[K, V], 0, len(keys))
+	for key := range keys {
+		it := c.items[key]
+		c.deleteLocked(it)
+		reason := ReasonRemoved
+		if it.expired(now) {
+			reason = ReasonExpired
+		}
+		evicted = append(evicted, eviction[K, V]{key: key, value: it.value, reason: reason})
+	}
+	c.mu.Unlock()
+
+	c.notify(evicted...)
+	return len(evicted)
+}
+
+// Tags returns the tags of key, or false if the key is missing or expired.
+func (c *TTLCache[K, V]) Tags(key K) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, found := c.items[key]
+	if !found || it.expired(c.clock.Now()) {
+		return nil, false
+	}
+	return append([]string(nil), it.tags...), true
+}
+
+// tagLocked adds it to the index of each of its tags. The caller must hold the write lock.
+func (c *TTLCache[K, V]) tagLocked(it *item[K, V]) {
+	if len(it.tags) == 0 {
+		return
+	}
+	if c.tags == nil {
+		c.tags = make(map[string]map[K]struct{})
+	}
+	for _, tag := range it.tags {
+		keys, found := c.tags[tag]
+		if !found {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		keys[it.key] = struct{}{}
+	}
+}
+
+// untagLocked removes it from the index of each of its tags, dropping indexes that become empty.
+// The caller must hold the write lock.
+func (c *TTLCache[K, V]) untagLocked(it *item[K, V]) {
+	for _, tag := range it.tags {
+		keys := c.tags[tag]
+		delete(keys, it.key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
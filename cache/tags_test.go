@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	c := NewWithOptions(Options[string, string]{DisableJanitor: true})
+	defer c.Close()
+
+	c.SetWithTags("profile:1", "profile", []string{"user:1"})
+	c.SetWithTags("feed:1", "feed", []string{"user:1", "feeds"}, time.Minute)
+	c.SetWithTags("feed:2", "feed", []string{"user:2", "feeds"})
+	c.Set("untagged", "value")
+
+	if n := c.InvalidateTag("user:1"); n != 2 {
+		t.Errorf("Expected 2 items to be invalidated, got %d", n)
+	}
+	for _, key := range []string{"profile:1", "feed:1"} {
+		if _, found := c.Get(key); found {
+			t.Errorf("Expected %s to be invalidated", key)
+		}
+	}
+	if tags, found := c.Tags("feed:2"); !found || len(tags) != 2 {
+		t.Errorf("Expected feed:2 to keep its tags, got %v", tags)
+	}
+	if n := c.InvalidateTag("user:1"); n != 0 {
+		t.Errorf("Expected nothing left under user:1, got %d", n)
+	}
+	if _, found := c.tags["user:1"]; found {
+		t.Error("Expected the empty tag index to be dropped")
+	}
+	if n := c.InvalidateTag("feeds"); n != 1 {
+		t.Errorf("Expected feed:2 to be invalidated, got %d", n)
+	}
+	if _, found := c.Get("untagged"); !found {
+		t.Error("Expected untagged items to be kept")
+	}
+}
+
+func TestTagIndexCleanup(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.SetWithTags("expiring", 1, []string{"a"}, time.Second)
+	c.SetWithTags("removed", 1, []string{"b"})
+	c.SetWithTags("replaced", 1, []string{"c"})
+	clock.Advance(2 * time.Second)
+	c.DeleteExpired()
+	c.Remove("removed")
+	c.Set("replaced", 2)
+
+	if len(c.tags) != 0 {
+		t.Errorf("Expected all tag indexes to be cleaned up, got %v", c.tags)
+	}
+
+	// Atomic updates keep the tags
+	c.SetWithTags("counter", 1, []string{"counters"})
+	Increment(c, "counter", 1)
+	if n := c.InvalidateTag("counters"); n != 1 {
+		t.Errorf("Expected the incremented counter to keep its tag, got %d", n)
+	}
+}
+
+func TestSnapshotKeepsTags(t *testing.T) {
+	src := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer src.Close()
+	src.SetWithTags("key", 1, []string{"tag"})
+
+	var buf bytes.Buffer
+	if err := src.SaveTo(&buf); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	dst := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer dst.Close()
+	if err := dst.LoadFrom(&buf); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if n := dst.InvalidateTag("tag"); n != 1 {
+		t.Errorf("Expected the restored item to keep its tag, got %d", n)
+	}
+}
+
+func TestUpdateKeepsTags(t *testing.T) {
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	defer c.Close()
+
+	c.SetWithTags("key", 1, []string{"user:1"})
+	c.Update("key", func(old int, ok bool) (int, bool) {
+		return old + 1, true
+	}, time.Minute)
+	if tags, _ := c.Tags("key"); len(tags) != 1 || tags[0] != "user:1" {
+		t.Errorf("Expected Update with a TTL to keep the tags, got %v", tags)
+	}
+	if n := c.InvalidateTag("user:1"); n != 1 {
+		t.Errorf("Expected the updated item to be invalidated, got %d", n)
+	}
+}
+
+func TestRefreshKeepsTags(t *testing.T) {
+	clock := newFakeClock()
+	loader := &countingLoader{}
+	c := NewWithOptions(Options[string, int]{
+		DisableJanitor: true,
+		Clock:          clock,
+		Loader:         loader.load,
+		RefreshAhead:   10 * time.Second,
+	})
+	defer c.Close()
+
+	c.SetWithTags("key", 0, []string{"user:1"}, time.Minute)
+	clock.Advance(55 * time.Second)
+	c.Get("key")
+	waitFor(t, func() bool {
+		value, _ := c.Get("key")
+		return value == 1
+	})
+	if n := c.InvalidateTag("user:1"); n != 1 {
+		t.Errorf("Expected the refreshed item to be invalidated, got %d", n)
+	}
+}
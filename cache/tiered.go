@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"github.com/huahuayu/kit/logger"
+	"sync"
+	"time"
+)
+
+// WriteMode decides when a TieredCache writes to its second tier.
+type WriteMode int
+
+const (
+	// WriteThrough writes to both tiers before Set returns.
+	WriteThrough WriteMode = iota
+	// WriteBehind writes to the first tier and queues the write to the second tier.
+	WriteBehind
+)
+
+// Notifier broadcasts key invalidations between instances, so a write on one instance
+// drops the stale first tier entries of its peers.
+// Publish announces that key changed. Subscribe registers fn for keys published by other instances
+// and returns a function that cancels the subscription.
+type Notifier[K comparable] interface {
+	Publish(key K) error
+	Subscribe(fn func(key K)) (cancel func())
+}
+
+// TieredOptions configures a TieredCache.
+// L1 configures the in-memory first tier.
+// L1TTL caps how long an entry lives in the first tier, so peers without a Notifier converge, zero means no cap.
+// An entry promoted from the second tier keeps its remaining second tier TTL when the second tier reports it with
+// a TTL(key) (time.Duration, bool) method like TTLCache. Otherwise it lives L1TTL, or one minute if L1TTL is zero,
+// so the first tier never serves it long after it expired in the second.
+// L2TTL is the second tier TTL of entries set without a TTL, zero means they do not expire.
+// WriteMode chooses between write-through and write-behind, QueueSize is the write-behind queue length (default 1024),
+// a full queue blocks writers.
+// Notifier is optional, it invalidates the first tier of other instances on every write.
+type TieredOptions[K comparable, V any] struct {
+	L1        Options[K, V]
+	L1TTL     time.Duration
+	L2TTL     time.Duration
+	WriteMode WriteMode
+	QueueSize int
+	Notifier  Notifier[K]
+}
+
+// TieredCache layers an in-memory TTLCache in front of another ICache such as a RedisCache.
+// Reads are served from the first tier and fall back to the second, promoting what they find.
+// A key with a write not yet applied to the second tier is only read from the first tier, and a promotion
+// never overwrites a write made while the second tier was read.
+type TieredCache[K comparable, V any] struct {
+	l1          *TTLCache[K, V]
+	l2          ICache[K, V]
+	mu          sync.Mutex
+	inflight    map[K]*tieredKey
+	l1TTL       time.Duration
+	l2TTL       time.Duration
+	notifier    Notifier[K]
+	unsubscribe func()
+	queue       chan tieredWrite[K, V]
+	queueMu     sync.RWMutex // held for reading to send to queue, for writing to close it
+	queueClosed bool
+	doneCh      chan struct{}
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// tieredWrite is a queued write-behind operation, a remove if del is set.
+type tieredWrite[K comparable, V any] struct {
+	key   K
+	value V
+	ttl   []time.Duration
+	del   bool
+}
+
+// tieredKey tracks the writes and second tier reads in flight on a key, it is dropped once there are none.
+type tieredKey struct {
+	writes  int    // writes not yet applied to the second tier
+	reads   int    // Gets reading the second tier
+	version uint64 // incremented by every write, a Get only promotes if it did not change
+}
+
+var _ ICache[string, any] = (*TieredCache[string, any])(nil)
+
+var defaultQueueSize = 1024
+
+// defaultPromotionTTL is the first tier TTL of entries promoted from a second tier that does not report TTLs,
+// when L1TTL is zero.
+var defaultPromotionTTL = time.Minute
+
+// ttlReader is implemented by second tiers that report the remaining TTL of a key, such as TTLCache.
+type ttlReader[K comparable] interface {
+	TTL(key K) (time.Duration, bool)
+}
+
+// NewTiered creates a TieredCache in front of l2. The TieredCache owns l2 and closes it on Close.
+func NewTiered[K comparable, V any](l2 ICache[K, V], opts TieredOptions[K, V]) *TieredCache[K, V] {
+	c := &TieredCache[K, V]{
+		l1:       NewWithOptions(opts.L1),
+		l2:       l2,
+		inflight: make(map[K]*tieredKey),
+		l1TTL:    opts.L1TTL,
+		l2TTL:    opts.L2TTL,
+		notifier: opts.Notifier,
+	}
+	if opts.WriteMode == WriteBehind {
+		size := opts.QueueSize
+		if size <= 0 {
+			size = defaultQueueSize
+		}
+		c.queue = make(chan tieredWrite[K, V], size)
+		c.doneCh = make(chan struct{})
+		go c.writeBehind()
+	}
+	if c.notifier != nil {
+		c.unsubscribe = c.notifier.Subscribe(func(key K) {
+			c.beginWrite(key)
+			c.l1.Remove(key)
+			c.endWrite(key)
+		})
+	}
+	return c
+}
+
+// L1 returns the in-memory first tier.
+func (c *TieredCache[K, V]) L1() *TTLCache[K, V] {
+	return c.l1
+}
+
+// Set writes key to both tiers, the first tier TTL is capped by L1TTL.
+func (c *TieredCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
+	c.beginWrite(key)
+	c.l1.Set(key, value, c.firstTierTTL(ttl...)...)
+	if len(ttl) == 0 && c.l2TTL > 0 {
+		ttl = []time.Duration{c.l2TTL}
+	}
+	c.writeL2(tieredWrite[K, V]{key: key, value: value, ttl: ttl})
+	c.publish(key)
+}
+
+// Get reads key from the first tier, or from the second tier on a miss and promotes it to the first.
+func (c *TieredCache[K, V]) Get(key K) (V, bool) {
+	if value, found := c.l1.Get(key); found {
+		return value, true
+	}
+	c.mu.Lock()
+	k := c.inflight[key]
+	if k != nil && k.writes > 0 {
+		// The second tier still holds what the pending write replaces
+		c.mu.Unlock()
+		var zeroV V
+		return zeroV, false
+	}
+	if k == nil {
+		k = &tieredKey{}
+		c.inflight[key] = k
+	}
+	k.reads++
+	version := k.version
+	c.mu.Unlock()
+
+	value, found := c.l2.Get(key)
+	var ttl []time.Duration
+	promote := false
+	if found {
+		ttl, promote = c.promotionTTL(key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Promoting under the lock orders it before any write that has not started yet
+	if promote && k.version == version {
+		c.l1.Set(key, value, ttl...)
+	}
+	k.reads--
+	c.releaseLocked(key, k)
+	return value, found
+}
+
+// promotionTTL returns the first tier TTL of key promoted from the second tier, false if it expired meanwhile.
+func (c *TieredCache[K, V]) promotionTTL(key K) ([]time.Duration, bool) {
+	if l2, ok := c.l2.(ttlReader[K]); ok {
+		ttl, found := l2.TTL(key)
+		if !found {
+			return nil, false
+		}
+		if ttl == NoExpiration {
+			return c.firstTierTTL(), true
+		}
+		return c.firstTierTTL(ttl), true
+	}
+	if c.l1TTL <= 0 {
+		return []time.Duration{defaultPromotionTTL}, true
+	}
+	return c.firstTierTTL(), true
+}
+
+// Remove deletes key from both tiers.
+func (c *TieredCache[K, V]) Remove(key K) {
+	c.beginWrite(key)
+	c.l1.Remove(key)
+	c.writeL2(tieredWrite[K, V]{key: key, del: true})
+	c.publish(key)
+}
+
+// Pop removes key from both tiers and returns its value.
+func (c *TieredCache[K, V]) Pop(key K) (V, bool) {
+	c.beginWrite(key)
+	value, found := c.l1.Pop(key)
+	if c.queue == nil {
+		if l2Value, l2Found := c.l2.Pop(key); l2Found {
+			value, found = l2Value, true
+		}
+		c.endWrite(key)
+	} else {
+		if !found {
+			value, found = c.l2.Get(key)
+		}
+		c.writeL2(tieredWrite[K, V]{key: key, del: true})
+	}
+	c.publish(key)
+	return value, found
+}
+
+// Close flushes pending write-behind writes, then closes both tiers and returns the error of the second.
+// The cache must not be used afterwards, write-behind writes made after Close are dropped.
+func (c *TieredCache[K, V]) Close() error {
+	c.closeOnce.Do(func() {
+		if c.unsubscribe != nil {
+			c.unsubscribe()
+		}
+		if c.queue != nil {
+			c.queueMu.Lock()
+			c.queueClosed = true
+			close(c.queue)
+			c.queueMu.Unlock()
+			<-c.doneCh
+		}
+		c.l1.Close()
+		c.closeErr = c.l2.Close()
+	})
+	return c.closeErr
+}
+
+// firstTierTTL returns the TTL of a first tier entry, the given TTL capped by L1TTL.
+func (c *TieredCache[K, V]) firstTierTTL(ttl ...time.Duration) []time.Duration {
+	if c.l1TTL <= 0 {
+		return ttl
+	}
+	if len(ttl) > 0 && ttl[0] < c.l1TTL {
+		return ttl
+	}
+	return []time.Duration{c.l1TTL}
+}
+
+// writeL2 queues w in write-behind mode, or applies it. Writes queued after Close are dropped, as the second
+// tier is closed. The write started by beginWrite ends once w is applied.
+func (c *TieredCache[K, V]) writeL2(w tieredWrite[K, V]) {
+	if c.queue != nil {
+		c.queueMu.RLock()
+		closed := c.queueClosed
+		if !closed {
+			c.queue <- w
+		}
+		c.queueMu.RUnlock()
+		if closed {
+			c.endWrite(w.key)
+		}
+		return
+	}
+	c.applyL2(w)
+	c.endWrite(w.key)
+}
+
+// beginWrite marks a write of key in flight until endWrite, so Get neither reads the second tier
+// nor promotes the value it read.
+func (c *TieredCache[K, V]) beginWrite(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := c.inflight[key]
+	if k == nil {
+		k = &tieredKey{}
+		c.inflight[key] = k
+	}
+	k.writes++
+	k.version++
+}
+
+func (c *TieredCache[K, V]) endWrite(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := c.inflight[key]
+	k.writes--
+	c.releaseLocked(key, k)
+}
+
+func (c *TieredCache[K, V]) releaseLocked(key K, k *tieredKey) {
+	if k.writes == 0 && k.reads == 0 {
+		delete(c.inflight, key)
+	}
+}
+
+func (c *TieredCache[K, V]) applyL2(w tieredWrite[K, V]) {
+	if w.del {
+		c.l2.Remove(w.key)
+		return
+	}
+	c.l2.Set(w.key, w.value, w.ttl...)
+}
+
+// writeBehind applies queued writes to the second tier in order until the queue is closed.
+func (c *TieredCache[K, V]) writeBehind() {
+	defer close(c.doneCh)
+	for w := range c.queue {
+		c.applyL2(w)
+		c.endWrite(w.key)
+	}
+}
+
+func (c *TieredCache[K, V]) publish(key K) {
+	if c.notifier == nil {
+		return
+	}
+	// A lost invalidation only leaves peers stale until their L1TTL, so it is logged rather than surfaced
+	if err := c.notifier.Publish(key); err != nil {
+		logger.Logger.Errorf("cache: publish invalidation of key %v failed with: %s", key, err)
+	}
+}
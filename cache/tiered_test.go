@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryHub connects memoryNotifiers in one process, like a broadcast channel between instances.
+type memoryHub[K comparable] struct {
+	mu   sync.Mutex
+	subs map[*memoryNotifier[K]]func(key K)
+}
+
+type memoryNotifier[K comparable] struct {
+	hub *memoryHub[K]
+}
+
+func newMemoryHub[K comparable]() *memoryHub[K] {
+	return &memoryHub[K]{subs: make(map[*memoryNotifier[K]]func(key K))}
+}
+
+func (h *memoryHub[K]) notifier() *memoryNotifier[K] {
+	return &memoryNotifier[K]{hub: h}
+}
+
+func (n *memoryNotifier[K]) Publish(key K) error {
+	n.hub.mu.Lock()
+	defer n.hub.mu.Unlock()
+	for sub, fn := range n.hub.subs {
+		if sub != n {
+			fn(key)
+		}
+	}
+	return nil
+}
+
+func (n *memoryNotifier[K]) Subscribe(fn func(key K)) func() {
+	n.hub.mu.Lock()
+	defer n.hub.mu.Unlock()
+	n.hub.subs[n] = fn
+	return func() {
+		n.hub.mu.Lock()
+		defer n.hub.mu.Unlock()
+		delete(n.hub.subs, n)
+	}
+}
+
+// sharedL2 wraps a TTLCache so several tiered caches can share it without closing it.
+type sharedL2[K comparable, V any] struct {
+	*TTLCache[K, V]
+}
+
+func (sharedL2[K, V]) Close() error {
+	return nil
+}
+
+func TestTieredReadThrough(t *testing.T) {
+	l2 := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	c := NewTiered[string, int](l2, TieredOptions[string, int]{L1: Options[string, int]{DisableJanitor: true}})
+	defer c.Close()
+
+	l2.Set("remote", 1)
+	if value, found := c.Get("remote"); !found || value != 1 {
+		t.Fatalf("Expected to read through to L2, got %v", value)
+	}
+	if value, found := c.L1().Get("remote"); !found || value != 1 {
+		t.Error("Expected the value to be promoted to L1")
+	}
+
+	c.Set("local", 2, time.Minute)
+	if value, found := l2.Get("local"); !found || value != 2 {
+		t.Error("Expected write-through to L2")
+	}
+	if value, found := c.Pop("local"); !found || value != 2 {
+		t.Errorf("Expected to pop 2, got %v", value)
+	}
+	if _, found := l2.Get("local"); found {
+		t.Error("Expected Pop to remove the key from L2")
+	}
+	c.Remove("remote")
+	if _, found := l2.Get("remote"); found {
+		t.Error("Expected Remove to remove the key from L2")
+	}
+}
+
+func TestTieredTTLs(t *testing.T) {
+	clock := newFakeClock()
+	l2 := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	c := NewTiered[string, int](l2, TieredOptions[string, int]{
+		L1:    Options[string, int]{DisableJanitor: true, Clock: clock},
+		L1TTL: time.Second,
+		L2TTL: time.Hour,
+	})
+	defer c.Close()
+
+	c.Set("key", 1)
+	if ttl, _ := c.L1().TTL("key"); ttl != time.Second {
+		t.Errorf("Expected L1 TTL to be capped at 1s, got %v", ttl)
+	}
+	if ttl, _ := l2.TTL("key"); ttl != time.Hour {
+		t.Errorf("Expected default L2 TTL of 1h, got %v", ttl)
+	}
+	c.Set("short", 1, time.Millisecond)
+	if ttl, _ := c.L1().TTL("short"); ttl != time.Millisecond {
+		t.Errorf("Expected a TTL below L1TTL to be kept, got %v", ttl)
+	}
+}
+
+// opaqueL2 hides the TTL method of a TTLCache.
+type opaqueL2[K comparable, V any] struct {
+	ICache[K, V]
+}
+
+func TestTieredPromotionTTL(t *testing.T) {
+	clock := newFakeClock()
+	l2 := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	c := NewTiered[string, int](l2, TieredOptions[string, int]{
+		L1: Options[string, int]{DisableJanitor: true, Clock: clock},
+	})
+	defer c.Close()
+
+	l2.Set("key", 1, 20*time.Millisecond)
+	if _, found := c.Get("key"); !found {
+		t.Fatal("Expected to read through to L2")
+	}
+	if ttl, _ := c.L1().TTL("key"); ttl != 20*time.Millisecond {
+		t.Errorf("Expected the promoted entry to keep the L2 TTL, got %v", ttl)
+	}
+	clock.Advance(40 * time.Millisecond)
+	if _, found := c.Get("key"); found {
+		t.Error("Expected the promoted entry to expire with L2")
+	}
+
+	opaque := NewTiered[string, int](opaqueL2[string, int]{NewWithOptions(Options[string, int]{DisableJanitor: true})},
+		TieredOptions[string, int]{L1: Options[string, int]{DisableJanitor: true}})
+	defer opaque.Close()
+	opaque.Set("key", 1)
+	opaque.L1().Remove("key")
+	opaque.Get("key")
+	if ttl, _ := opaque.L1().TTL("key"); ttl <= 0 || ttl > defaultPromotionTTL {
+		t.Errorf("Expected the default promotion TTL without L2 TTLs, got %v", ttl)
+	}
+}
+
+func TestTieredWriteBehind(t *testing.T) {
+	l2 := NewWithOptions(Options[int, int]{DisableJanitor: true})
+	c := NewTiered[int, int](sharedL2[int, int]{l2}, TieredOptions[int, int]{
+		L1:        Options[int, int]{DisableJanitor: true},
+		WriteMode: WriteBehind,
+		QueueSize: 4,
+	})
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+	c.Remove(0)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := l2.Len(); n != 99 {
+		t.Errorf("Expected Close to flush all writes in order, L2 has %d items", n)
+	}
+	if _, found := l2.Get(0); found {
+		t.Error("Expected the queued remove to be applied after the set")
+	}
+}
+
+func TestTieredWriteBehindAfterClose(t *testing.T) {
+	l2 := NewWithOptions(Options[int, int]{DisableJanitor: true})
+	defer l2.Close()
+	c := NewTiered[int, int](sharedL2[int, int]{l2}, TieredOptions[int, int]{
+		L1:        Options[int, int]{DisableJanitor: true},
+		WriteMode: WriteBehind,
+		QueueSize: 1,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			c.Set(i, i)
+		}
+	}()
+	c.Close()
+	wg.Wait()
+	c.Set(1, 10)
+	c.Remove(2)
+	c.Pop(3)
+	if value, _ := l2.Get(1); value == 10 {
+		t.Error("Expected writes after Close to be dropped")
+	}
+}
+
+// hookL2 runs hooks after the Get and before the Remove of a TTLCache.
+type hookL2[K comparable, V any] struct {
+	*TTLCache[K, V]
+	afterGet     func(key K)
+	beforeRemove func(key K)
+}
+
+func (h hookL2[K, V]) Get(key K) (V, bool) {
+	value, found := h.TTLCache.Get(key)
+	if h.afterGet != nil {
+		h.afterGet(key)
+	}
+	return value, found
+}
+
+func (h hookL2[K, V]) Remove(key K) {
+	if h.beforeRemove != nil {
+		h.beforeRemove(key)
+	}
+	h.TTLCache.Remove(key)
+}
+
+func TestTieredRemoveThenGet(t *testing.T) {
+	l2 := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	release := make(chan struct{})
+	c := NewTiered[string, int](hookL2[string, int]{TTLCache: l2, beforeRemove: func(string) { <-release }}, TieredOptions[string, int]{
+		L1:        Options[string, int]{DisableJanitor: true},
+		WriteMode: WriteBehind,
+	})
+	defer c.Close()
+
+	c.Set("key", 1)
+	waitFor(t, func() bool {
+		_, found := l2.TTL("key")
+		return found
+	})
+	c.Remove("key")
+	if value, found := c.Get("key"); found {
+		t.Errorf("Expected a miss while the remove is queued, got %v", value)
+	}
+	close(release)
+	waitFor(t, func() bool {
+		_, found := l2.TTL("key")
+		return !found
+	})
+	if value, found := c.Get("key"); found {
+		t.Errorf("Expected the removed key to stay removed, got %v", value)
+	}
+}
+
+func TestTieredPromotionKeepsNewerWrite(t *testing.T) {
+	l2 := NewWithOptions(Options[string, int]{DisableJanitor: true})
+	hooked := &hookL2[string, int]{TTLCache: l2}
+	c := NewTiered[string, int](hooked, TieredOptions[string, int]{L1: Options[string, int]{DisableJanitor: true}})
+	defer c.Close()
+
+	l2.Set("key", 1)
+	// A write lands while Get reads the old value from L2
+	hooked.afterGet = func(key string) {
+		hooked.afterGet = nil
+		c.Set(key, 2)
+	}
+	c.Get("key")
+	if value, _ := c.L1().Get("key"); value != 2 {
+		t.Errorf("Expected the promotion not to overwrite the newer write, got %v", value)
+	}
+}
+
+func TestTieredInvalidation(t *testing.T) {
+	hub := newMemoryHub[string]()
+	l2 := NewWithOptions(Options[string, string]{DisableJanitor: true})
+	defer l2.Close()
+	newInstance := func() *TieredCache[string, string] {
+		return NewTiered[string, string](sharedL2[string, string]{l2}, TieredOptions[string, string]{
+			L1:       Options[string, string]{DisableJanitor: true},
+			Notifier: hub.notifier(),
+		})
+	}
+	a, b := newInstance(), newInstance()
+	defer a.Close()
+	defer b.Close()
+
+	a.Set("user", "v1")
+	if value, _ := b.Get("user"); value != "v1" {
+		t.Fatalf("Expected b to read v1, got %v", value)
+	}
+	a.Set("user", "v2")
+	if _, found := b.L1().Get("user"); found {
+		t.Fatal("Expected a's write to invalidate b's L1")
+	}
+	if value, _ := b.Get("user"); value != "v2" {
+		t.Errorf("Expected b to read v2, got %v", value)
+	}
+}
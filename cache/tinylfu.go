@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// tinyLFU is a W-TinyLFU policy. New keys enter a small window LRU, and keys leaving the window are admitted
+// into the main cache only if a count-min sketch estimates they are used more often than the main cache victim.
+// The main cache is a segmented LRU: admitted keys start in probation and move to protected on their next hit,
+// so a key has to be used twice to push out the established hot keys.
+type tinyLFU[K comparable] struct {
+	seed         maphash.Seed
+	sketch       *countMinSketch
+	maxCost      int64
+	windowMax    int64
+	protectedMax int64
+	segments     [3]*list.List // front is the most recently used
+	costs        [3]int64
+	entries      map[K]*list.Element
+}
+
+type segment int
+
+const (
+	windowSegment segment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUEntry[K comparable] struct {
+	key     K
+	cost    int64
+	hash    uint64
+	segment segment
+}
+
+var (
+	// windowPercent of MaxCost is the window LRU, protectedPercent of the rest is the protected segment
+	windowPercent    int64 = 1
+	protectedPercent int64 = 80
+	// minSketchWidth and maxSketchWidth bound the counters per sketch row, the sketch grows with the number of keys
+	minSketchWidth = 64
+	maxSketchWidth = 1 << 22
+)
+
+func newTinyLFU[K comparable](maxCost int64) *tinyLFU[K] {
+	p := &tinyLFU[K]{
+		seed:      maphash.MakeSeed(),
+		sketch:    newCountMinSketch(minSketchWidth),
+		maxCost:   maxCost,
+		windowMax: maxCost * windowPercent / 100,
+		entries:   make(map[K]*list.Element),
+	}
+	if p.windowMax < 1 {
+		p.windowMax = 1
+	}
+	p.protectedMax = (maxCost - p.windowMax) * protectedPercent / 100
+	for i := range p.segments {
+		p.segments[i] = list.New()
+	}
+	return p
+}
+
+func (p *tinyLFU[K]) add(key K, cost int64) []K {
+	p.remove(key)
+	if cost > p.maxCost {
+		return []K{key}
+	}
+	if len(p.entries) >= p.sketch.width() && p.sketch.width() < maxSketchWidth {
+		// Resizing loses the frequencies, which only happens while the cache warms up
+		p.sketch = newCountMinSketch(p.sketch.width() * 2)
+	}
+	h := hashKey(p.seed, key)
+	p.sketch.increment(h)
+	p.push(windowSegment, &tinyLFUEntry[K]{key: key, cost: cost, hash: h})
+
+	var victims []K
+	for p.costs[windowSegment] > p.windowMax {
+		candidate := p.unlink(p.segments[windowSegment].Back())
+		victims = p.admit(candidate, victims)
+	}
+	return victims
+}
+
+// admit moves a key leaving the window into probation if it beats the main cache victims it displaces,
+// and appends whatever it evicts, possibly the candidate itself, to victims.
+func (p *tinyLFU[K]) admit(candidate *tinyLFUEntry[K], victims []K) []K {
+	mainMax := p.maxCost - p.windowMax
+	for p.costs[probationSegment]+p.costs[protectedSegment]+candidate.cost > mainMax {
+		e := p.segments[probationSegment].Back()
+		if e == nil {
+			e = p.segments[protectedSegment].Back()
+		}
+		if e == nil || p.sketch.estimate(candidate.hash) <= p.sketch.estimate(e.Value.(*tinyLFUEntry[K]).hash) {
+			return append(victims, candidate.key)
+		}
+		victims = append(victims, p.unlink(e).key)
+	}
+	p.push(probationSegment, candidate)
+	return victims
+}
+
+func (p *tinyLFU[K]) access(key K) {
+	e, found := p.entries[key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*tinyLFUEntry[K])
+	p.sketch.increment(entry.hash)
+	if entry.segment != probationSegment {
+		p.segments[entry.segment].MoveToFront(e)
+		return
+	}
+	p.push(protectedSegment, p.unlink(e))
+	for p.costs[protectedSegment] > p.protectedMax {
+		p.push(probationSegment, p.unlink(p.segments[protectedSegment].Back()))
+	}
+}
+
+func (p *tinyLFU[K]) remove(key K) {
+	if e, found := p.entries[key]; found {
+		p.unlink(e)
+	}
+}
+
+func (p *tinyLFU[K]) cost() int64 {
+	return p.costs[windowSegment] + p.costs[probationSegment] + p.costs[protectedSegment]
+}
+
+// push adds entry at the front of segment s.
+func (p *tinyLFU[K]) push(s segment, entry *tinyLFUEntry[K]) {
+	entry.segment = s
+	p.entries[entry.key] = p.segments[s].PushFront(entry)
+	p.costs[s] += entry.cost
+}
+
+// unlink removes e from its segment and returns its entry.
+func (p *tinyLFU[K]) unlink(e *list.Element) *tinyLFUEntry[K] {
+	entry := e.Value.(*tinyLFUEntry[K])
+	p.segments[entry.segment].Remove(e)
+	p.costs[entry.segment] -= entry.cost
+	delete(p.entries, entry.key)
+	return entry
+}
+
+// countMinSketch estimates key frequencies in a fixed amount of memory. Each of its rows counts every key
+// in one counter picked by a different hash, collisions only inflate counters, so the smallest one is the estimate.
+// Counters saturate at 15 and are all halved every 10 increments per counter, so old popularity fades.
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// sketchSeeds are odd multipliers deriving the row indexes from one key hash.
+var sketchSeeds = [4]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// newCountMinSketch creates a sketch with width counters per row, width must be a power of two.
+func newCountMinSketch(width int) *countMinSketch {
+	s := &countMinSketch{mask: uint64(width - 1), resetAt: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch) width() int {
+	return len(s.rows[0])
+}
+
+func (s *countMinSketch) index(i int, h uint64) uint64 {
+	h *= sketchSeeds[i]
+	return (h ^ h>>32) & s.mask
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	for i, row := range s.rows {
+		if idx := s.index(i, h); row[idx] < 15 {
+			row[idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.age()
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	lowest := uint8(15)
+	for i, row := range s.rows {
+		if count := row[s.index(i, h)]; count < lowest {
+			lowest = count
+		}
+	}
+	return lowest
+}
+
+// age halves all counters.
+func (s *countMinSketch) age() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// zipfTrace returns n keys drawn from a Zipf distribution over keys, with a scan of scanLen never repeated keys
+// inserted every scanEvery draws when scanEvery is positive.
+func zipfTrace(n, keys int, scanEvery, scanLen int) []uint64 {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, uint64(keys-1))
+	trace := make([]uint64, 0, n)
+	next := uint64(keys)
+	for i := 0; i < n; i++ {
+		if scanEvery > 0 && i%scanEvery == 0 {
+			for j := 0; j < scanLen; j++ {
+				trace = append(trace, next)
+				next++
+			}
+		}
+		trace = append(trace, zipf.Uint64())
+	}
+	return trace
+}
+
+// hitRatio replays trace against a cache holding capacity items, setting every key that misses.
+func hitRatio(policy EvictionPolicy, capacity int64, trace []uint64) float64 {
+	c := NewWithOptions(Options[uint64, int]{DisableJanitor: true, MaxCost: capacity, Policy: policy})
+	defer c.Close()
+	for _, key := range trace {
+		if _, found := c.Get(key); !found {
+			c.Set(key, 0)
+		}
+	}
+	return c.Stats().HitRatio()
+}
+
+func TestTinyLFUResistsScans(t *testing.T) {
+	trace := zipfTrace(100000, 10000, 1000, 500)
+	lru := hitRatio(PolicyLRU, 500, trace)
+	tinyLFU := hitRatio(PolicyTinyLFU, 500, trace)
+	if tinyLFU <= lru {
+		t.Errorf("Expected TinyLFU to beat LRU on a scanned Zipf trace, got %.3f vs %.3f", tinyLFU, lru)
+	}
+}
+
+func TestTinyLFUZipf(t *testing.T) {
+	trace := zipfTrace(100000, 10000, 0, 0)
+	lru := hitRatio(PolicyLRU, 500, trace)
+	tinyLFU := hitRatio(PolicyTinyLFU, 500, trace)
+	if tinyLFU < lru {
+		t.Errorf("Expected TinyLFU to match LRU on a Zipf trace, got %.3f vs %.3f", tinyLFU, lru)
+	}
+}
+
+func TestTinyLFUCost(t *testing.T) {
+	c := NewWithOptions(Options[int, []byte]{DisableJanitor: true, MaxCost: 1000, Policy: PolicyTinyLFU})
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		c.Set(i%50, make([]byte, i%40))
+		c.Get(i % 7)
+		if cost := c.Stats().Cost; cost > 1000 {
+			t.Fatalf("Expected the cost to stay under 1000, got %d", cost)
+		}
+	}
+	var total int64
+	c.Range(func(key int, value []byte) bool {
+		total += int64(len(value))
+		return true
+	})
+	if cost := c.Stats().Cost; cost != total {
+		t.Errorf("Expected the tracked cost %d to match the items held %d", cost, total)
+	}
+
+	c.Set(-1, make([]byte, 1001))
+	if _, found := c.Get(-1); found {
+		t.Error("Expected a value larger than MaxCost not to be kept")
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(64)
+	for i := 0; i < 20; i++ {
+		s.increment(1)
+	}
+	s.increment(2)
+	if n := s.estimate(1); n != 15 {
+		t.Errorf("Expected the counter to saturate at 15, got %d", n)
+	}
+	if n := s.estimate(2); n < 1 {
+		t.Errorf("Expected at least 1, got %d", n)
+	}
+	s.age()
+	if n := s.estimate(1); n != 7 {
+		t.Errorf("Expected aging to halve the counter, got %d", n)
+	}
+}
+
+func benchmarkHitRatio(b *testing.B, policy EvictionPolicy, scanEvery int) {
+	trace := zipfTrace(100000, 10000, scanEvery, 500)
+	b.ResetTimer()
+	var ratio float64
+	for i := 0; i < b.N; i++ {
+		ratio = hitRatio(policy, 500, trace)
+	}
+	b.ReportMetric(ratio*100, "hit%")
+}
+
+func BenchmarkZipfLRU(b *testing.B) {
+	benchmarkHitRatio(b, PolicyLRU, 0)
+}
+
+func BenchmarkZipfTinyLFU(b *testing.B) {
+	benchmarkHitRatio(b, PolicyTinyLFU, 0)
+}
+
+func BenchmarkZipfScanLRU(b *testing.B) {
+	benchmarkHitRatio(b, PolicyLRU, 1000)
+}
+
+func BenchmarkZipfScanTinyLFU(b *testing.B) {
+	benchmarkHitRatio(b, PolicyTinyLFU, 1000)
+}
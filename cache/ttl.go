@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"container/heap"
+	"math/rand"
+	"time"
+)
+
+// NoExpiration is the TTL reported for items that never expire.
+const NoExpiration time.Duration = -1
+
+// TTL returns the remaining TTL of key, or NoExpiration if it never expires.
+// It returns false if the key is missing or expired.
+func (c *TTLCache[K, V]) TTL(key K) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	it, found := c.items[key]
+	if !found || it.expired(now) {
+		return 0, false
+	}
+	if it.expiry.IsZero() {
+		return NoExpiration, true
+	}
+	return it.expiry.Sub(now), true
+}
+
+// Expire sets the TTL of an existing key to ttl from now, a ttl of zero or less expires it immediately.
+// It returns false if the key is missing or already expired.
+func (c *TTLCache[K, V]) Expire(key K, ttl time.Duration) bool {
+	c.mu.Lock()
+	now := c.clock.Now()
+	it, found := c.items[key]
+	if !found || it.expired(now) {
+		c.mu.Unlock()
+		return false
+	}
+	if ttl > 0 {
+		it.ttl, it.baseTTL = ttl, ttl
+		c.setExpiryLocked(it, now.Add(ttl))
+		c.mu.Unlock()
+		return true
+	}
+	c.deleteLocked(it)
+	c.mu.Unlock()
+
+	c.notify(eviction[K, V]{key: key, value: it.value, reason: ReasonExpired})
+	return true
+}
+
+// Persist removes the TTL of key so it never expires. It returns false if the key is missing or expired.
+func (c *TTLCache[K, V]) Persist(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, found := c.items[key]
+	if !found || it.expired(c.clock.Now()) {
+		return false
+	}
+	it.ttl, it.baseTTL = 0, 0
+	c.setExpiryLocked(it, time.Time{})
+	return true
+}
+
+// Touch restarts the TTL of key from now, as if it had just been set. It returns false if the key is missing or expired.
+func (c *TTLCache[K, V]) Touch(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	it, found := c.items[key]
+	if !found || it.expired(now) {
+		return false
+	}
+	if it.ttl > 0 {
+		c.setExpiryLocked(it, now.Add(it.ttl))
+	}
+	return true
+}
+
+// setExpiryLocked moves it to a new expiry, a zero expiry means it no longer expires. The caller must hold the write lock.
+func (c *TTLCache[K, V]) setExpiryLocked(it *item[K, V], expiry time.Time) {
+	it.expiry = expiry
+	switch {
+	case expiry.IsZero() && it.index >= 0:
+		heap.Remove(&c.expiries, it.index)
+	case expiry.IsZero():
+	case it.index >= 0:
+		heap.Fix(&c.expiries, it.index)
+	default:
+		heap.Push(&c.expiries, it)
+	}
+}
+
+// jittered adds the configured random jitter to ttl.
+func (c *TTLCache[K, V]) jittered(ttl time.Duration) time.Duration {
+	if c.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(int64(c.jitter)))
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock, SlidingExpiration: true})
+	defer c.Close()
+
+	c.Set("session", 1, time.Minute)
+	for i := 0; i < 5; i++ {
+		clock.Advance(50 * time.Second)
+		if _, found := c.Get("session"); !found {
+			t.Fatalf("Expected session to slide on access %d", i)
+		}
+	}
+	clock.Advance(61 * time.Second)
+	if _, found := c.Get("session"); found {
+		t.Error("Expected idle session to expire")
+	}
+}
+
+func TestTTLOperations(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	c.Set("key", 1, time.Minute)
+	c.Set("forever", 1)
+	clock.Advance(10 * time.Second)
+
+	if ttl, found := c.TTL("key"); !found || ttl != 50*time.Second {
+		t.Errorf("Expected 50s remaining, got %v", ttl)
+	}
+	if ttl, found := c.TTL("forever"); !found || ttl != NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v", ttl)
+	}
+	if _, found := c.TTL("missing"); found {
+		t.Error("Expected no TTL for a missing key")
+	}
+
+	if !c.Touch("key") {
+		t.Fatal("Expected Touch to succeed")
+	}
+	if ttl, _ := c.TTL("key"); ttl != time.Minute {
+		t.Errorf("Expected Touch to restart the TTL, got %v", ttl)
+	}
+
+	if !c.Expire("forever", time.Second) {
+		t.Fatal("Expected Expire to succeed")
+	}
+	if !c.Persist("key") {
+		t.Fatal("Expected Persist to succeed")
+	}
+	clock.Advance(2 * time.Minute)
+	c.DeleteExpired()
+	if _, found := c.Get("forever"); found {
+		t.Error("Expected Expire to give the key a TTL")
+	}
+	if _, found := c.Get("key"); !found {
+		t.Error("Expected Persist to remove the TTL")
+	}
+	if len(c.expiries) != 0 {
+		t.Errorf("Expected empty expiry heap, got %d items", len(c.expiries))
+	}
+
+	if !c.Expire("key", 0) {
+		t.Fatal("Expected Expire with zero TTL to succeed")
+	}
+	if _, found := c.Get("key"); found {
+		t.Error("Expected Expire with zero TTL to remove the key")
+	}
+	if c.Touch("missing") || c.Persist("missing") || c.Expire("missing", time.Second) {
+		t.Error("Expected operations on a missing key to fail")
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, int]{DisableJanitor: true, Clock: clock, TTLJitter: time.Minute})
+	defer c.Close()
+
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		c.Set(key, i, time.Hour)
+		ttl, _ := c.TTL(key)
+		if ttl < time.Hour || ttl >= time.Hour+time.Minute {
+			t.Fatalf("Expected TTL in [1h, 1h1m), got %v", ttl)
+		}
+		distinct[ttl] = true
+	}
+	if len(distinct) < 50 {
+		t.Errorf("Expected jittered TTLs to differ, got %d distinct values", len(distinct))
+	}
+}
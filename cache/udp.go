@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// UDPTransportOptions configures a UDPTransport.
+// Addr is the address to receive on. A multicast group address such as "239.0.0.1:7946" joins the group
+// on Interface (nil means the system default) and broadcasts to it, any other address receives unicast datagrams
+// and broadcasts to each of Peers instead, e.g. where multicast is not routed.
+// Secret is shared by every peer, it prefixes each datagram with its HMAC-SHA256 and datagrams without a valid
+// one are dropped. Nil means datagrams are neither signed nor checked, so the port must not be reachable by others.
+type UDPTransportOptions struct {
+	Addr      string
+	Peers     []string
+	Interface *net.Interface
+	Secret    []byte
+}
+
+// UDPTransport is a Transport sending each message as a single UDP datagram, over multicast or unicast.
+// Delivery is best effort: a lost datagram leaves the peers stale until their entries expire.
+type UDPTransport struct {
+	conn   *net.UDPConn
+	secret []byte
+	mu     sync.RWMutex
+	peers  []*net.UDPAddr
+	doneCh chan struct{}
+}
+
+var _ Transport = (*UDPTransport)(nil)
+
+// maxDatagram is the largest UDP payload over IPv4.
+const maxDatagram = 65507
+
+// NewUDPTransport creates a UDPTransport receiving on opts.Addr.
+func NewUDPTransport(opts UDPTransportOptions) (*UDPTransport, error) {
+	addr, err := net.ResolveUDPAddr("udp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &UDPTransport{secret: opts.Secret}
+	if addr.IP.IsMulticast() {
+		if t.conn, err = net.ListenMulticastUDP("udp", opts.Interface, addr); err != nil {
+			return nil, err
+		}
+		t.peers = []*net.UDPAddr{addr}
+		return t, nil
+	}
+	if t.conn, err = net.ListenUDP("udp", addr); err != nil {
+		return nil, err
+	}
+	for _, peer := range opts.Peers {
+		if err = t.AddPeer(peer); err != nil {
+			t.conn.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// LocalAddr returns the address the transport receives on.
+func (t *UDPTransport) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+// AddPeer adds a unicast peer address to broadcast to.
+func (t *UDPTransport) AddPeer(peer string) error {
+	addr, err := net.ResolveUDPAddr("udp", peer)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.peers = append(t.peers, addr)
+	return nil
+}
+
+// Broadcast sends msg to the multicast group or to every peer.
+func (t *UDPTransport) Broadcast(msg []byte) error {
+	if t.secret != nil {
+		msg = append(signature(t.secret, msg), msg...)
+	}
+	if len(msg) > maxDatagram {
+		return fmt.Errorf("cache: invalidation of %d bytes does not fit in a datagram", len(msg))
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var errs []error
+	for _, peer := range t.peers {
+		if _, err := t.conn.WriteToUDP(msg, peer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Listen starts receiving datagrams in the background and calls fn with each of them.
+// With a secret, datagrams not signed with it are dropped.
+func (t *UDPTransport) Listen(fn func(msg []byte)) error {
+	if t.doneCh != nil {
+		return errors.New("cache: udp transport is already listening")
+	}
+	t.doneCh = make(chan struct{})
+	go func() {
+		defer close(t.doneCh)
+		buf := make([]byte, maxDatagram)
+		for {
+			n, _, err := t.conn.ReadFromUDP(buf)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			msg, ok := t.verify(buf[:n])
+			if !ok {
+				continue
+			}
+			fn(append([]byte(nil), msg...))
+		}
+	}()
+	return nil
+}
+
+// verify returns the message of datagram, false if the transport has a secret and datagram is not signed with it.
+func (t *UDPTransport) verify(datagram []byte) ([]byte, bool) {
+	if t.secret == nil {
+		return datagram, true
+	}
+	if len(datagram) < sha256.Size {
+		return nil, false
+	}
+	mac, msg := datagram[:sha256.Size], datagram[sha256.Size:]
+	return msg, hmac.Equal(mac, signature(t.secret, msg))
+}
+
+// Close closes the socket and waits for the receiving goroutine to exit.
+func (t *UDPTransport) Close() error {
+	err := t.conn.Close()
+	if t.doneCh != nil {
+		<-t.doneCh
+	}
+	return err
+}
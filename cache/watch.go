@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// EventType is the kind of change a watch Event describes.
+type EventType int
+
+const (
+	// EventSet means the key was set, Value is the new value.
+	EventSet EventType = iota
+	// EventRemoved means the key was deleted by Remove, Pop or Clear, Value is the old value.
+	EventRemoved
+	// EventExpired means the key outlived its TTL and was purged, Value is the old value.
+	EventExpired
+	// EventEvicted means the key was dropped to keep the cache within its capacity, Value is the old value.
+	EventEvicted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventRemoved:
+		return "removed"
+	case EventExpired:
+		return "expired"
+	case EventEvicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a key delivered to a Subscription.
+type Event[K comparable, V any] struct {
+	Type  EventType
+	Key   K
+	Value V
+}
+
+// Subscription receives the change events of the keys it watches on C until it is closed.
+// Events are delivered without blocking the cache: when C is full the event is dropped and counted by Dropped.
+type Subscription[K comparable, V any] struct {
+	C       <-chan Event[K, V]
+	ch      chan Event[K, V]
+	filter  func(key K) bool
+	dropped atomic.Uint64
+	hub     *watchHub[K, V]
+}
+
+// watchHub holds the subscriptions of a cache.
+type watchHub[K comparable, V any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[K, V]]struct{}
+	count  atomic.Int32 // lets publish skip the lock while nobody watches
+	closed bool
+}
+
+var defaultWatchBuffer = 64
+
+// Subscribe returns a Subscription to the changes of the keys matched by filter, nil matches every key.
+// buffer is the capacity of its channel, zero or less means 64.
+// Expired events are sent when the janitor or DeleteExpired purges the item, not when a Get finds it expired.
+// Events are sent after the cache lock is released, so the events of concurrent writes to a key may arrive
+// in either order. C is closed when the subscription or the cache is closed.
+func (c *TTLCache[K, V]) Subscribe(filter func(key K) bool, buffer int) *Subscription[K, V] {
+	if buffer <= 0 {
+		buffer = defaultWatchBuffer
+	}
+	ch := make(chan Event[K, V], buffer)
+	s := &Subscription[K, V]{C: ch, ch: ch, filter: filter, hub: &c.watchers}
+
+	c.watchers.mu.Lock()
+	defer c.watchers.mu.Unlock()
+	if c.watchers.closed {
+		close(ch)
+		return s
+	}
+	if c.watchers.subs == nil {
+		c.watchers.subs = make(map[*Subscription[K, V]]struct{})
+	}
+	c.watchers.subs[s] = struct{}{}
+	c.watchers.count.Add(1)
+	return s
+}
+
+// Watch returns a Subscription to the changes of key.
+func (c *TTLCache[K, V]) Watch(key K) *Subscription[K, V] {
+	return c.Subscribe(func(k K) bool { return k == key }, 0)
+}
+
+// Dropped returns the number of events dropped because C was full.
+func (s *Subscription[K, V]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops the subscription and closes C. It is idempotent.
+func (s *Subscription[K, V]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, found := s.hub.subs[s]; found {
+		delete(s.hub.subs, s)
+		s.hub.count.Add(-1)
+		close(s.ch)
+	}
+}
+
+// publish delivers e to every subscription matching its key without blocking.
+func (h *watchHub[K, V]) publish(e Event[K, V]) {
+	if h.count.Load() == 0 {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(e.Key) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// close closes every subscription, later subscriptions are closed right away.
+func (h *watchHub[K, V]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		close(s.ch)
+	}
+	h.subs = nil
+	h.count.Store(0)
+	h.closed = true
+}
+
+// eventType maps an eviction reason to its event, a replaced item has no event of its own
+// since the EventSet of its successor describes the change.
+func eventType(reason EvictReason) (EventType, bool) {
+	switch reason {
+	case ReasonRemoved:
+		return EventRemoved, true
+	case ReasonExpired:
+		return EventExpired, true
+	case ReasonEvicted:
+		return EventEvicted, true
+	default:
+		return 0, false
+	}
+}
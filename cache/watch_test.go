@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWithOptions(Options[string, string]{DisableJanitor: true, Clock: clock})
+	defer c.Close()
+
+	sub := c.Watch("session")
+	defer sub.Close()
+
+	c.Set("other", "ignored")
+	c.Set("session", "v1", time.Minute)
+	c.Set("session", "v2", time.Minute)
+	clock.Advance(time.Hour)
+	c.DeleteExpired()
+	c.Set("session", "v3")
+	c.Remove("session")
+
+	want := []Event[string, string]{
+		{Type: EventSet, Key: "session", Value: "v1"},
+		{Type: EventSet, Key: "session", Value: "v2"},
+		{Type: EventExpired, Key: "session", Value: "v2"},
+		{Type: EventSet, Key: "session", Value: "v3"},
+		{Type: EventRemoved, Key: "session", Value: "v3"},
+	}
+	for i, w := range want {
+		select {
+		case e := <-sub.C:
+			if e != w {
+				t.Errorf("Event %d: expected %+v, got %+v", i, w, e)
+			}
+		default:
+			t.Fatalf("Event %d: expected %+v, got nothing", i, w)
+		}
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("Expected no more events, got %+v", e)
+	default:
+	}
+}
+
+func TestSubscribeFilterAndEvictions(t *testing.T) {
+	c := NewWithOptions(Options[int, int]{DisableJanitor: true, MaxCost: 2})
+	defer c.Close()
+
+	sub := c.Subscribe(func(key int) bool { return key%2 == 1 }, 0)
+	defer sub.Close()
+
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Set(3, 3)
+	c.SetMany(map[int]int{7: 7})
+	c.Update(5, func(old int, ok bool) (int, bool) { return 5, true })
+
+	want := []Event[int, int]{
+		{Type: EventSet, Key: 1, Value: 1},
+		{Type: EventSet, Key: 3, Value: 3},
+		{Type: EventEvicted, Key: 1, Value: 1},
+		{Type: EventSet, Key: 7, Value: 7},
+		{Type: EventSet, Key: 5, Value: 5},
+		{Type: EventEvicted, Key: 3, Value: 3},
+	}
+	for i, w := range want {
+		if e := <-sub.C; e != w {
+			t.Errorf("Event %d: expected %+v, got %+v", i, w, e)
+		}
+	}
+}
+
+func TestSubscriptionDropsWhenFull(t *testing.T) {
+	c := NewWithOptions(Options[int, int]{DisableJanitor: true})
+	sub := c.Subscribe(nil, 2)
+
+	for i := 0; i < 5; i++ {
+		c.Set(i, i)
+	}
+	if n := sub.Dropped(); n != 3 {
+		t.Errorf("Expected 3 dropped events, got %d", n)
+	}
+
+	c.Close()
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Expected the 2 buffered events before C is closed, got %d", n)
+	}
+	sub.Close()
+
+	late := c.Watch(1)
+	if _, open := <-late.C; open {
+		t.Error("Expected a subscription to a closed cache to be closed")
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	c := NewWithOptions(Options[int, int]{DisableJanitor: true})
+	defer c.Close()
+
+	sub := c.Watch(1)
+	sub.Close()
+	sub.Close()
+	c.Set(1, 1)
+	if _, open := <-sub.C; open {
+		t.Error("Expected C to be closed")
+	}
+}
+
+func BenchmarkSetWithSubscriber(b *testing.B) {
+	c := NewWithOptions(Options[int, int]{DisableJanitor: true})
+	defer c.Close()
+	sub := c.Watch(-1)
+	defer sub.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(i, i)
+	}
+}
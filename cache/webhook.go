@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookTransport is a Transport over HTTP. Broadcast POSTs each message to the webhook URL of every peer,
+// and the transport is itself the http.Handler receiving the messages of the peers, to be mounted at that URL.
+type WebhookTransport struct {
+	peers   []string
+	client  *http.Client
+	secret  []byte
+	mu      sync.RWMutex
+	handler func(msg []byte)
+}
+
+var _ Transport = (*WebhookTransport)(nil)
+
+// WebhookSignatureHeader is the header carrying the "sha256=" hex HMAC of a message signed with a secret.
+const WebhookSignatureHeader = "X-Cache-Signature"
+
+// WebhookOptions configures a WebhookTransport.
+// Secret is shared by every peer, it signs the messages posted and ServeHTTP rejects the messages not signed
+// with it. Nil means messages are neither signed nor checked, so the handler must not be reachable by others.
+type WebhookOptions struct {
+	Secret []byte
+}
+
+// maxWebhookBody bounds the size of a received message.
+var maxWebhookBody int64 = 1 << 20
+
+// NewWebhookTransport creates a WebhookTransport posting to the peers URLs with client, nil means a client
+// with a five second timeout.
+func NewWebhookTransport(peers []string, client *http.Client, opts ...WebhookOptions) *WebhookTransport {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	t := &WebhookTransport{peers: peers, client: client}
+	if len(opts) > 0 {
+		t.secret = opts[0].Secret
+	}
+	return t
+}
+
+// Broadcast POSTs msg to every peer concurrently and returns the errors of the peers that failed.
+func (t *WebhookTransport) Broadcast(msg []byte) error {
+	errs := make([]error, len(t.peers))
+	var wg sync.WaitGroup
+	for i, peer := range t.peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			errs[i] = t.post(peer, msg)
+		}(i, peer)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (t *WebhookTransport) post(peer string, msg []byte) error {
+	req, err := http.NewRequest(http.MethodPost, peer, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.secret != nil {
+		req.Header.Set(WebhookSignatureHeader, t.sign(msg))
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cache: webhook %s returned %s", peer, resp.Status)
+	}
+	return nil
+}
+
+// sign returns the signature header value of msg.
+func (t *WebhookTransport) sign(msg []byte) string {
+	return "sha256=" + hex.EncodeToString(signature(t.secret, msg))
+}
+
+// Listen registers fn for the messages received by ServeHTTP.
+func (t *WebhookTransport) Listen(fn func(msg []byte)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = fn
+	return nil
+}
+
+// ServeHTTP receives a message POSTed by a peer, with a secret it answers 401 to a message not signed with it.
+func (t *WebhookTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	msg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if t.secret != nil && !hmac.Equal([]byte(r.Header.Get(WebhookSignatureHeader)), []byte(t.sign(msg))) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	t.mu.RLock()
+	handler := t.handler
+	t.mu.RUnlock()
+	if handler == nil {
+		http.Error(w, "cache: no invalidation listener", http.StatusServiceUnavailable)
+		return
+	}
+	handler(msg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Close stops handing messages to the listener, ServeHTTP then answers 503.
+func (t *WebhookTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = nil
+	return nil
+}
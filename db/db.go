@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
@@ -37,3 +38,8 @@ func NewPqClientByDSN(dsnString string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// HealthCheck returns a check pinging db, to register as a readiness check of the http server.
+func HealthCheck(db *sql.DB) func(ctx context.Context) error {
+	return db.PingContext
+}
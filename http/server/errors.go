@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// APIError is an error answered with a business code of the Response envelope.
+// Codes are registered once with Register, which gives their HTTP status and default message, and call sites
+// return the registered error, optionally wrapping the cause with Wrap.
+type APIError struct {
+	Code    string
+	Status  int
+	Message string
+	Cause   error
+}
+
+// ExposeInternalErrors shows the cause of server errors in responses, which helps in development but leaks
+// internals, so it is off by default. It should be set once at startup.
+var ExposeInternalErrors bool
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*APIError)
+)
+
+var (
+	ErrBadRequest      = Register("4000", http.StatusBadRequest, "bad request")
+	ErrUnauthorized    = Register("4010", http.StatusUnauthorized, "unauthorized")
+	ErrForbidden       = Register("4030", http.StatusForbidden, "forbidden")
+	ErrNotFound        = Register("4040", http.StatusNotFound, "not found")
+	ErrConflict        = Register("4090", http.StatusConflict, "conflict")
+	ErrTooLarge        = Register("4130", http.StatusRequestEntityTooLarge, "request body too large")
+	ErrTooManyRequests = Register("4290", http.StatusTooManyRequests, "too many requests")
+	ErrInternal        = Register("5000", http.StatusInternalServerError, "internal server error")
+	ErrUnavailable     = Register("5030", http.StatusServiceUnavailable, "service unavailable")
+)
+
+// Register registers code with its HTTP status and default message and returns its APIError.
+// It panics if code is already registered, so two packages cannot give a code different meanings.
+func Register(code string, status int, message string) *APIError {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, found := registry[code]; found {
+		panic(fmt.Sprintf("server: error code %s is already registered", code))
+	}
+	e := &APIError{Code: code, Status: status, Message: message}
+	registry[code] = e
+	return e
+}
+
+// Lookup returns the APIError registered for code.
+func Lookup(code string) (*APIError, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	e, found := registry[code]
+	return e, found
+}
+
+// Codes returns the registered errors sorted by code.
+func Codes() []*APIError {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	codes := make([]*APIError, 0, len(registry))
+	for _, e := range registry {
+		codes = append(codes, e)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+func (e *APIError) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is an APIError with the same code, so errors.Is(err, ErrNotFound) matches
+// the copies made by Wrap and WithMessage.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by cause.
+func (e *APIError) Wrap(cause error) *APIError {
+	wrapped := *e
+	wrapped.Cause = cause
+	return &wrapped
+}
+
+// WithMessage returns a copy of e with the message formatted from format and args.
+func (e *APIError) WithMessage(format string, args ...any) *APIError {
+	withMessage := *e
+	withMessage.Message = fmt.Sprintf(format, args...)
+	return &withMessage
+}
+
+// ResponseFromError writes err as a Response envelope with the code and status of its APIError.
+// An error that is not an APIError is answered as ErrInternal, or ErrUnavailable if its deadline exceeded.
+// Client errors show their cause. Server errors are logged with the request ID set by RequestID, and their
+// response only shows the registered message unless ExposeInternalErrors is set.
+func ResponseFromError(w http.ResponseWriter, err error) {
+	var e *APIError
+	if !errors.As(err, &e) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			e = ErrUnavailable.Wrap(err)
+		} else {
+			e = ErrInternal.Wrap(err)
+		}
+	}
+
+	msg := e.Error()
+	if e.Status >= http.StatusInternalServerError {
+		entry := logger.Logger.WithFields(logger.Fields{"code": e.Code, "request_id": w.Header().Get(RequestIDHeader)})
+		entry.Errorf("server: request failed with: %s", err)
+		if !ExposeInternalErrors {
+			msg = e.Message
+		}
+	}
+	ResponseErr(w, e.Code, msg, e.Status)
+}
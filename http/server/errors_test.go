@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	errGone := Register("4100", http.StatusGone, "gone")
+	if e, found := Lookup("4100"); !found || e != errGone {
+		t.Errorf("Expected Lookup to find the registered error, got %v", e)
+	}
+	codes := Codes()
+	if len(codes) < 9 || codes[0].Code != "4000" {
+		t.Errorf("Expected the codes sorted, got %v", codes)
+	}
+
+	cause := errors.New("row deleted")
+	err := fmt.Errorf("loading order: %w", errGone.Wrap(cause))
+	if !errors.Is(err, errGone) || !errors.Is(err, cause) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected err to match its code and cause only")
+	}
+	if errGone.Cause != nil {
+		t.Errorf("Expected Wrap not to modify the registered error")
+	}
+	if msg := errGone.Wrap(cause).Error(); msg != "gone: row deleted" {
+		t.Errorf("Expected gone: row deleted, got %q", msg)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a code twice to panic")
+		}
+	}()
+	Register("4100", http.StatusGone, "gone again")
+}
+
+func TestResponseFromError(t *testing.T) {
+	var entries []logger.Fields
+	defaultLogger := logger.Logger
+	logger.Logger = &recordLogger{entries: &entries}
+	defer func() {
+		logger.Logger = defaultLogger
+		ExposeInternalErrors = false
+	}()
+
+	respond := func(err error) (*httptest.ResponseRecorder, Response) {
+		w := httptest.NewRecorder()
+		w.Header().Set(RequestIDHeader, "req-1")
+		ResponseFromError(w, err)
+		return w, decode(t, w)
+	}
+
+	w, resp := respond(ErrBadRequest.Wrap(errors.New("name is required")))
+	if w.Code != http.StatusBadRequest || resp.Code != "4000" || resp.Msg != "bad request: name is required" {
+		t.Errorf("Expected the client error with its cause, got %d %+v", w.Code, resp)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected client errors not to be logged, got %v", entries)
+	}
+
+	w, resp = respond(errors.New("connection refused"))
+	if w.Code != http.StatusInternalServerError || resp.Msg != "internal server error" {
+		t.Errorf("Expected the cause of internal errors to be hidden by default, got %d %+v", w.Code, resp)
+	}
+	if len(entries) != 1 || entries[0]["request_id"] != "req-1" || entries[0]["code"] != "5000" {
+		t.Errorf("Expected the internal error to be logged with the request ID, got %v", entries)
+	}
+
+	ExposeInternalErrors = true
+	if _, resp = respond(ErrInternal.Wrap(errors.New("connection refused"))); resp.Msg != "internal server error: connection refused" {
+		t.Errorf("Expected the cause to be exposed when enabled, got %q", resp.Msg)
+	}
+	if _, resp = respond(ErrNotFound.Wrap(errors.New("no such user"))); resp.Msg != "not found: no such user" {
+		t.Errorf("Expected client errors to keep their cause, got %q", resp.Msg)
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBodyBytes bounds the JSON body decoded by Handle, a larger body is answered with ErrTooLarge.
+// It should be set once at startup.
+var MaxBodyBytes int64 = 1 << 20
+
+// Validator is implemented by request types that check themselves once they are bound.
+type Validator interface {
+	Validate() error
+}
+
+// Handle adapts fn to an http.HandlerFunc. The request is bound into a Req: the JSON body is decoded first,
+// then fields tagged `path:"name"`, `query:"name"` or `header:"Name"` are set from the path parameters, the query
+// string and the headers. Fields tagged `validate:"required"` must not be zero, and a Req implementing Validator
+// is validated. A body over MaxBodyBytes is answered with ErrTooLarge, a request that cannot be bound or is
+// invalid with ErrBadRequest, otherwise fn is called and its Resp is written with ResponseOK, or its error with
+// ResponseFromError.
+// Handle panics if a tagged field has a type that cannot be parsed from a string.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) http.HandlerFunc {
+	b := newBinder(reflect.TypeOf((*Req)(nil)).Elem())
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := b.bind(w, r, &req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				ResponseFromError(w, ErrTooLarge.Wrap(err))
+				return
+			}
+			ResponseFromError(w, ErrBadRequest.Wrap(err))
+			return
+		}
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			ResponseFromError(w, err)
+			return
+		}
+		ResponseOK(w, resp, "")
+	}
+}
+
+// binder binds requests into values of a type.
+type binder struct {
+	fields []boundField
+}
+
+// boundField is a field set from the request or checked by validation.
+type boundField struct {
+	index    []int
+	source   string // "path", "query", "header" or "" for the body
+	name     string
+	required bool
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+func newBinder(t reflect.Type) *binder {
+	b := &binder{}
+	if t.Kind() == reflect.Struct {
+		b.collect(t, nil)
+	}
+	return b
+}
+
+// collect adds the fields of t, including the ones of embedded structs, to b.
+func (b *binder) collect(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			b.collect(f.Type, fieldIndex)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		field := boundField{index: fieldIndex, name: f.Name}
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			field.name = name
+		}
+		for _, source := range []string{"path", "query", "header"} {
+			if name := f.Tag.Get(source); name != "" {
+				if !parsable(f.Type) {
+					panic(fmt.Sprintf("server: field %s of %s cannot be bound from the %s", f.Name, t, source))
+				}
+				field.source, field.name = source, name
+				break
+			}
+		}
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			if strings.TrimSpace(rule) == "required" {
+				field.required = true
+			}
+		}
+		if field.source != "" || field.required {
+			b.fields = append(b.fields, field)
+		}
+	}
+}
+
+// bind decodes r into ptr and validates it. The body is read through w, so its size is bounded by MaxBodyBytes.
+func (b *binder) bind(w http.ResponseWriter, r *http.Request, ptr any) error {
+	if r.Body != nil && r.Body != http.NoBody {
+		body := http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+		if err := json.NewDecoder(body).Decode(ptr); err != nil && err != io.EOF {
+			return fmt.Errorf("invalid JSON body: %w", err)
+		}
+	}
+
+	v := reflect.ValueOf(ptr).Elem()
+	var query map[string][]string
+	for _, f := range b.fields {
+		var values []string
+		switch f.source {
+		case "path":
+			if value := Param(r, f.name); value != "" {
+				values = []string{value}
+			}
+		case "query":
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[f.name]
+		case "header":
+			values = r.Header.Values(f.name)
+		}
+		if len(values) > 0 {
+			if err := setField(v.FieldByIndex(f.index), values); err != nil {
+				return fmt.Errorf("invalid %s parameter %q: %w", f.source, f.name, err)
+			}
+		}
+	}
+
+	for _, f := range b.fields {
+		if f.required && v.FieldByIndex(f.index).IsZero() {
+			return fmt.Errorf("%s is required", f.name)
+		}
+	}
+	// The method set of the pointer includes the value receiver methods of Req
+	if validator, ok := ptr.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// parsable reports whether a field of type t can be set from strings.
+func parsable(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setField sets v from values, a slice gets all of them and other types the first one.
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() != reflect.Slice {
+		return setValue(v, values[0])
+	}
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := setValue(slice.Index(i), value); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Paging struct {
+	Page  int      `query:"page"`
+	Sizes []uint16 `query:"size"`
+}
+
+type updateUser struct {
+	Paging
+	ID      string         `path:"id"`
+	Token   string         `header:"X-Token" validate:"required"`
+	Timeout *time.Duration `query:"timeout"`
+	Name    string         `json:"name" validate:"required"`
+	Age     int            `json:"age"`
+	Since   time.Time      `query:"since"`
+}
+
+func (u updateUser) Validate() error {
+	if u.Age < 0 {
+		return errors.New("age must not be negative")
+	}
+	return nil
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not decode %q: %v", w.Body.String(), err)
+	}
+	return resp
+}
+
+func TestHandle(t *testing.T) {
+	var got updateUser
+	router := NewRouter()
+	router.HandleFunc("PUT /users/{id}", Handle(func(ctx context.Context, req updateUser) (map[string]string, error) {
+		got = req
+		return map[string]string{"id": req.ID, "name": req.Name}, nil
+	}))
+
+	send := func(query, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/users/42"+query, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("X-Token", token)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send("?page=2&size=10&size=20&timeout=1s&since=2024-01-02T00:00:00Z", `{"name":"ann","age":30}`, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %q", w.Code, w.Body.String())
+	}
+	if resp := decode(t, w); resp.Code != "0000" || resp.Data.(map[string]any)["name"] != "ann" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if got.ID != "42" || got.Token != "secret" || got.Name != "ann" || got.Age != 30 || got.Page != 2 {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if len(got.Sizes) != 2 || got.Sizes[1] != 20 || got.Timeout == nil || *got.Timeout != time.Second || got.Since.Year() != 2024 {
+		t.Errorf("Unexpected request %+v", got)
+	}
+
+	tests := map[string]struct {
+		query string
+		body  string
+		token string
+	}{
+		"bad JSON":         {"", `{"name":`, "secret"},
+		"bad query":        {"?page=two", `{"name":"ann"}`, "secret"},
+		"overflow":         {"?size=70000", `{"name":"ann"}`, "secret"},
+		"missing header":   {"", `{"name":"ann"}`, ""},
+		"missing body":     {"", ``, "secret"},
+		"Validate failing": {"", `{"name":"ann","age":-1}`, "secret"},
+	}
+	for name, tt := range tests {
+		w := send(tt.query, tt.body, tt.token)
+		if w.Code != http.StatusBadRequest || decode(t, w).Code != "4000" {
+			t.Errorf("%s: expected a 400 envelope, got %d %q", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	handler := Handle(func(ctx context.Context, req struct{}) (any, error) {
+		return nil, ErrNotFound.WithMessage("user %d not found", 7)
+	})
+	w := serve(handler, "GET", "/")
+	if resp := decode(t, w); w.Code != http.StatusNotFound || resp.Code != "4040" || resp.Msg != "user 7 not found" {
+		t.Errorf("Expected the APIError envelope, got %d %+v", w.Code, resp)
+	}
+
+	handler = Handle(func(ctx context.Context, req struct{}) (any, error) {
+		return nil, errors.New("database is down")
+	})
+	w = serve(handler, "GET", "/")
+	if resp := decode(t, w); w.Code != http.StatusInternalServerError || resp.Code != "5000" || strings.Contains(resp.Msg, "database") {
+		t.Errorf("Expected a 500 hiding the error, got %d %+v", w.Code, resp)
+	}
+
+	handler = Handle(func(ctx context.Context, req struct{}) (any, error) {
+		return nil, context.DeadlineExceeded
+	})
+	if w := serve(handler, "GET", "/"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 on a deadline, got %d", w.Code)
+	}
+
+	handler = Handle(func(ctx context.Context, req struct{ Name string }) (any, error) {
+		return nil, nil
+	})
+	body := `{"Name": "` + strings.Repeat("a", int(MaxBodyBytes)) + `"}`
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if resp := decode(t, w); w.Code != http.StatusRequestEntityTooLarge || resp.Code != "4130" {
+		t.Errorf("Expected a 413 for a body over MaxBodyBytes, got %d %+v", w.Code, resp)
+	}
+}
+
+func TestHandlePanicsOnUnsupportedField(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic")
+		}
+	}()
+	Handle(func(ctx context.Context, req struct {
+		Filter map[string]string `query:"filter"`
+	}) (any, error) {
+		return nil, nil
+	})
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check reports whether a component works, it should return when ctx is done.
+type Check func(ctx context.Context) error
+
+// HealthOptions configures a Health.
+// Timeout bounds every check, zero means 5 seconds. A check still running afterwards fails.
+// CacheTTL is how long the result of a check is reused, so frequent probes do not hammer the components,
+// zero means every probe runs the checks.
+type HealthOptions struct {
+	Timeout  time.Duration
+	CacheTTL time.Duration
+}
+
+// Health runs named checks for the liveness and readiness probes.
+// Liveness checks tell whether the process works at all and should be restarted if not, readiness checks
+// tell whether it can serve traffic, typically by reaching its dependencies.
+type Health struct {
+	timeout   time.Duration
+	cacheTTL  time.Duration
+	mu        sync.RWMutex
+	liveness  []*healthCheck
+	readiness []*healthCheck
+	ready     func() bool
+}
+
+// HealthReport is the JSON body of a probe, Status is "ok" or "fail".
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the result of a check in a HealthReport.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// healthCheck is a registered check with its last result.
+type healthCheck struct {
+	name      string
+	check     Check
+	mu        sync.Mutex
+	result    CheckResult
+	checkedAt time.Time
+}
+
+var defaultCheckTimeout = 5 * time.Second
+
+// NewHealth creates a Health without checks.
+func NewHealth(opts ...HealthOptions) *Health {
+	var opt HealthOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	h := &Health{timeout: opt.Timeout, cacheTTL: opt.CacheTTL}
+	if h.timeout <= 0 {
+		h.timeout = defaultCheckTimeout
+	}
+	return h
+}
+
+// AddLivenessCheck registers check under name for both probes, it panics if name is already registered.
+func (h *Health) AddLivenessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mustBeNew(name)
+	h.liveness = append(h.liveness, &healthCheck{name: name, check: check})
+}
+
+// AddReadinessCheck registers check under name for the readiness probe, it panics if name is already registered.
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mustBeNew(name)
+	h.readiness = append(h.readiness, &healthCheck{name: name, check: check})
+}
+
+func (h *Health) mustBeNew(name string) {
+	for _, checks := range [][]*healthCheck{h.liveness, h.readiness} {
+		for _, c := range checks {
+			if c.name == name {
+				panic(fmt.Sprintf("server: health check %s is already registered", name))
+			}
+		}
+	}
+}
+
+// Live runs the liveness checks.
+func (h *Health) Live(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := h.liveness
+	h.mu.RUnlock()
+	return h.run(ctx, checks)
+}
+
+// Ready runs the liveness and readiness checks, it fails while the server is not ready.
+func (h *Health) Ready(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := append(append([]*healthCheck(nil), h.liveness...), h.readiness...)
+	h.mu.RUnlock()
+	report := h.run(ctx, checks)
+	if h.ready != nil && !h.ready() {
+		report.Status = "fail"
+		report.Checks["server"] = CheckResult{Status: "fail", Error: "server is not ready", Duration: "0s"}
+	}
+	return report
+}
+
+// LiveHandler answers the liveness report, with a 503 if it fails.
+func (h *Health) LiveHandler() http.Handler {
+	return healthHandler(h.Live)
+}
+
+// ReadyHandler answers the readiness report, with a 503 if it fails.
+func (h *Health) ReadyHandler() http.Handler {
+	return healthHandler(h.Ready)
+}
+
+func healthHandler(probe func(ctx context.Context) HealthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// run runs checks concurrently and aggregates their results.
+func (h *Health) run(ctx context.Context, checks []*healthCheck) HealthReport {
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *healthCheck) {
+			defer wg.Done()
+			results[i] = h.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
+
+// runCheck runs c unless its cached result is still fresh. Concurrent probes wait for the same run.
+func (h *Health) runCheck(ctx context.Context, c *healthCheck) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h.cacheTTL > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < h.cacheTTL {
+		return c.result
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errCh <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		errCh <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		// The check ignores its context, it is left to finish in the background
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+
+	result := CheckResult{Status: "ok", Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status, result.Error = "fail", err.Error()
+	}
+	// A probe that went away says nothing about the component, its result is not cached
+	if parent.Err() == nil {
+		c.result, c.checkedAt = result, time.Now()
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	h := NewHealth(HealthOptions{Timeout: 50 * time.Millisecond})
+	var dbErr error
+	h.AddLivenessCheck("goroutines", func(ctx context.Context) error { return nil })
+	h.AddReadinessCheck("db", func(ctx context.Context) error { return dbErr })
+	h.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	live := h.Live(context.Background())
+	if live.Status != "ok" || len(live.Checks) != 1 {
+		t.Errorf("Expected only the liveness check to pass, got %+v", live)
+	}
+
+	dbErr = errors.New("connection refused")
+	ready := h.Ready(context.Background())
+	if ready.Status != "fail" || len(ready.Checks) != 3 {
+		t.Fatalf("Expected the readiness probe to fail, got %+v", ready)
+	}
+	if ready.Checks["db"].Error != "connection refused" || ready.Checks["goroutines"].Status != "ok" {
+		t.Errorf("Unexpected results %+v", ready.Checks)
+	}
+	if ready.Checks["slow"].Error != "timed out after 50ms" {
+		t.Errorf("Expected the slow check to time out, got %+v", ready.Checks["slow"])
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a duplicate check to panic")
+		}
+	}()
+	h.AddLivenessCheck("db", func(ctx context.Context) error { return nil })
+}
+
+func TestHealthCache(t *testing.T) {
+	h := NewHealth(HealthOptions{CacheTTL: time.Hour})
+	var runs atomic.Int32
+	h.AddReadinessCheck("counted", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	h.AddReadinessCheck("panicky", func(ctx context.Context) error {
+		panic("boom")
+	})
+	for i := 0; i < 3; i++ {
+		h.Ready(context.Background())
+	}
+	if runs.Load() != 1 {
+		t.Errorf("Expected the result to be cached, got %d runs", runs.Load())
+	}
+	if result := h.Ready(context.Background()).Checks["panicky"]; result.Error != "panic: boom" {
+		t.Errorf("Expected a panic to fail the check, got %+v", result)
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	s := New()
+	var healthy atomic.Bool
+	healthy.Store(true)
+	s.Health().AddReadinessCheck("dep", func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errors.New("down")
+		}
+		return nil
+	})
+	base := startServer(t, s, nil)
+
+	probe := func(path string) (int, HealthReport) {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("Could not send GET request: %v", err)
+		}
+		defer resp.Body.Close()
+		var report HealthReport
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("Could not decode the report: %v", err)
+		}
+		return resp.StatusCode, report
+	}
+
+	if status, report := probe("/readyz"); status != http.StatusOK || report.Status != "ok" {
+		t.Errorf("Expected ready, got %d %+v", status, report)
+	}
+	healthy.Store(false)
+	if status, report := probe("/readyz"); status != http.StatusServiceUnavailable || report.Checks["dep"].Error != "down" {
+		t.Errorf("Expected not ready, got %d %+v", status, report)
+	}
+	if status, _ := probe("/healthz"); status != http.StatusOK {
+		t.Errorf("Expected readiness checks not to fail liveness, got %d", status)
+	}
+
+	s.(*Server).ready.Store(false)
+	if status, report := probe("/readyz"); status != http.StatusServiceUnavailable || report.Checks["server"].Status != "fail" {
+		t.Errorf("Expected not ready while shutting down, got %d %+v", status, report)
+	}
+}
+
+func TestServerHealthAfterStart(t *testing.T) {
+	s := New()
+	startServer(t, s, nil)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a first Health call after start to panic")
+		}
+	}()
+	s.Health()
+}
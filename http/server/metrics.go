@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"github.com/huahuayu/kit/metrics"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics records the requests of the server on reg, nil means metrics.Default: http_requests_total and
+// http_request_duration_seconds by method, route and status, and http_requests_in_flight.
+// The route is the pattern that matched, such as /users/{id}, or "unmatched", and unusual methods are
+// recorded as OTHER, so clients cannot create series at will. Use it first with Server.Use, so it sees
+// the status of the requests answered by the other middlewares, including Recover.
+func Metrics(reg *metrics.Registry) Middleware {
+	if reg == nil {
+		reg = metrics.Default
+	}
+	requests := reg.NewCounter("http_requests_total", "HTTP requests by method, route and status.",
+		"method", "route", "status")
+	duration := reg.NewHistogram("http_request_duration_seconds", "HTTP request latencies in seconds.", nil,
+		"method", "route", "status")
+	inFlight := reg.NewGauge("http_requests_in_flight", "HTTP requests being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			route := new(atomic.Pointer[string])
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+
+			pattern := "unmatched"
+			if p := route.Load(); p != nil {
+				pattern = *p
+			}
+			method, status := metricMethod(r.Method), strconv.Itoa(sw.Status())
+			requests.Inc(method, pattern, status)
+			duration.Observe(time.Since(start).Seconds(), method, pattern, status)
+		})
+	}
+}
+
+// metricMethod returns method if it is a standard method, OTHER otherwise.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
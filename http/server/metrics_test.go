@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/huahuayu/kit/metrics"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	s := New().(*Server)
+	s.Use(Metrics(reg), Recover)
+	s.Router().HandleFunc("GET /users/{id}", text("user"))
+	s.Router().HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	s.Router().Handle("GET /metrics", reg.Handler())
+	handler := chain(s.Router(), s.middlewares...)
+
+	serve(handler, "GET", "/users/1")
+	serve(handler, "GET", "/users/2")
+	serve(handler, "POST", "/users/2")
+	serve(handler, "GET", "/panic")
+	serve(handler, "GET", "/missing")
+	serve(handler, "BREW", "/missing")
+
+	body := serve(handler, "GET", "/metrics").Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/users/{id}",status="200"} 2`,
+		`http_requests_total{method="POST",route="/users/{id}",status="405"} 1`,
+		`http_requests_total{method="GET",route="/panic",status="500"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="OTHER",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 2`,
+		`http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %s in\n%s", line, body)
+		}
+	}
+
+	// Registering the middleware again, e.g. for a second server, reuses the metrics
+	Metrics(reg)
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader is the header RequestID reads and sets.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// Recover turns a panic in the handler into a 500 envelope and logs it with its stack.
+// http.ErrAbortHandler is re-panicked, it is the way to abort a response on purpose.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger.Logger.Errorf("server: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+			ResponseErr(w, ErrInternal.Code, ErrInternal.Message, ErrInternal.Status)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestID propagates the X-Request-Id header of the request, or generates one if it is missing,
+// sets it on the response and stores it in the request context for RequestIDFromContext.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID set by RequestID, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// AccessLog logs every request to l with its method, path, status, size, duration, client address and request ID.
+// Server errors are logged at error level.
+func AccessLog(l logger.ILogger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			entry := l.WithFields(logger.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"status":     sw.Status(),
+				"bytes":      sw.bytes,
+				"duration":   time.Since(start).String(),
+				"remote":     r.RemoteAddr,
+				"request_id": w.Header().Get(RequestIDHeader),
+			})
+			if sw.Status() >= 500 {
+				entry.Error("http request")
+			} else {
+				entry.Info("http request")
+			}
+		})
+	}
+}
+
+// statusWriter records the status and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Status returns the status written, 200 if the handler wrote nothing.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Timeout cancels the request context after d and answers a 503 envelope if the handler has not finished by then.
+// The response is buffered until the handler returns, handlers should stop when their context is done.
+// A panic of the handler is raised again for Recover, or only logged if the 503 has already been answered.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			// Seeded with the headers set by outer middlewares, such as the request ID
+			tw := &timeoutWriter{header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicCh := make(chan any, 1)
+			go func() {
+				defer func() {
+					p := recover()
+					if p == nil {
+						close(done)
+						return
+					}
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+					if !tw.timedOut {
+						panicCh <- p
+						return
+					}
+					// Nobody waits for the handler anymore, so the panic is only logged
+					if p != http.ErrAbortHandler {
+						logger.Logger.Errorf("server: panic serving %s %s after its timeout: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+			}()
+
+			select {
+			case p := <-panicCh:
+				// Re-panic on the serving goroutine so Recover can handle it
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for k, v := range tw.header {
+					w.Header()[k] = v
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				// A panic sent before the lock was taken is still raised, later ones are logged by the handler goroutine
+				select {
+				case p := <-panicCh:
+					panic(p)
+				default:
+				}
+				tw.timedOut = true
+				ResponseErr(w, ErrUnavailable.Code, "request timeout", ErrUnavailable.Status)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of a handler run by Timeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 && !w.timedOut {
+		w.status = status
+	}
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// CORSOptions configures CORS.
+// AllowedOrigins lists the origins allowed to call the server, "*" allows any origin but not with AllowCredentials.
+// AllowedMethods are the methods allowed in preflight requests, empty means GET, HEAD, POST, PUT, PATCH and DELETE.
+// AllowedHeaders are the request headers allowed in preflight requests, empty allows the ones requested.
+// ExposedHeaders are the response headers readable by the browser.
+// AllowCredentials allows cookies and authorization headers, MaxAge is how long a preflight result may be cached.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS answers preflight requests and sets the CORS headers of requests from allowed origins.
+// It should wrap the whole server with Server.Use, so preflight requests never reach the router.
+// It panics if any origin is allowed together with credentials, which would let every site make credentialed requests.
+func CORS(opts CORSOptions) Middleware {
+	anyOrigin := false
+	origins := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+	if anyOrigin && opts.AllowCredentials {
+		panic("server: CORS cannot allow credentials from any origin, list the allowed origins")
+	}
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	if methods == "" {
+		methods = "GET, HEAD, POST, PUT, PATCH, DELETE"
+	}
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			w.Header().Add("Vary", "Origin")
+			allowed := origin != "" && (anyOrigin || origins[strings.ToLower(origin)])
+			if allowed {
+				if anyOrigin {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+				if opts.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			if !preflight {
+				if allowed && exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				if headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+					w.Header().Set("Access-Control-Allow-Headers", requested)
+				}
+				if opts.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// RealIP replaces r.RemoteAddr with the client IP when the request comes through trusted proxies.
+// trustedProxies are IPs or CIDRs, it panics if one is invalid. When the peer is trusted, the client is the
+// rightmost untrusted address of X-Forwarded-For, or X-Real-Ip if there is no X-Forwarded-For.
+// Headers from untrusted peers are ignored, since any client can set them.
+func RealIP(trustedProxies ...string) Middleware {
+	var trusted []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(fmt.Sprintf("server: invalid trusted proxy %q: %s", proxy, err))
+		}
+		trusted = append(trusted, network)
+	}
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		if ip == nil {
+			return false
+		}
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer = r.RemoteAddr
+			}
+			if isTrusted(peer) {
+				if ip := clientIP(r, isTrusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the client address a trusted proxy forwarded, or "" if there is none.
+func clientIP(r *http.Request, isTrusted func(addr string) bool) string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			return ""
+		}
+		if !isTrusted(hop) || i == 0 {
+			return hop
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return ""
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/huahuayu/kit/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordLogger records the fields and messages it logs.
+type recordLogger struct {
+	logger.ILogger
+	fields  logger.Fields
+	entries *[]logger.Fields
+}
+
+func (l *recordLogger) WithFields(fields logger.Fields) logger.ILogger {
+	return &recordLogger{fields: fields, entries: l.entries}
+}
+
+func (l *recordLogger) Info(args ...any) {
+	*l.entries = append(*l.entries, l.fields)
+}
+
+func (l *recordLogger) Error(args ...any) {
+	*l.entries = append(*l.entries, l.fields)
+}
+
+func (l *recordLogger) Errorf(format string, args ...any) {
+	*l.entries = append(*l.entries, l.fields)
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s := New().(*Server)
+	s.Use(tag("server"))
+	s.Router().Group("/api", tag("group")).HandleFunc("GET /items", text("items"), tag("route"))
+	handler := chain(s.Router(), s.middlewares...)
+
+	if w := serve(handler, "GET", "/api/items"); w.Body.String() != "items" {
+		t.Errorf("Expected items, got %q", w.Body.String())
+	}
+	if strings.Join(calls, ",") != "server,group,route" {
+		t.Errorf("Expected server, group then route middlewares, got %v", calls)
+	}
+	calls = nil
+	serve(handler, "GET", "/missing")
+	if strings.Join(calls, ",") != "server" {
+		t.Errorf("Expected server middlewares on a 404, got %v", calls)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	w := serve(handler, "GET", "/")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != "5000" {
+		t.Errorf("Expected an error envelope, got %q", w.Body.String())
+	}
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to be re-panicked, got %v", p)
+		}
+	}()
+	serve(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})), "GET", "/")
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	w := serve(handler, "GET", "/")
+	if id := w.Header().Get(RequestIDHeader); len(id) != 32 || id != seen {
+		t.Errorf("Expected a generated ID in the response and context, got %q and %q", id, seen)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "abc")
+	handler.ServeHTTP(w, r)
+	if id := w.Header().Get(RequestIDHeader); id != "abc" || seen != "abc" {
+		t.Errorf("Expected the incoming ID to be propagated, got %q and %q", id, seen)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var entries []logger.Fields
+	l := &recordLogger{entries: &entries}
+	handler := RequestID(AccessLog(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+	w := serve(handler, "POST", "/users")
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry["method"] != "POST" || entry["path"] != "/users" || entry["status"] != 201 || entry["bytes"] != 5 {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+	if entry["request_id"] != w.Header().Get(RequestIDHeader) {
+		t.Errorf("Expected request_id %q, got %v", w.Header().Get(RequestIDHeader), entry["request_id"])
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Write([]byte("late"))
+	}))
+	w := serve(slow, "GET", "/")
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "late") {
+		t.Errorf("Expected a 503 envelope, got %d %q", w.Code, w.Body.String())
+	}
+
+	fast := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("done"))
+	}))
+	w = serve(fast, "GET", "/")
+	if w.Code != http.StatusAccepted || w.Body.String() != "done" || w.Header().Get("X-Test") != "1" {
+		t.Errorf("Expected the buffered response, got %d %q", w.Code, w.Body.String())
+	}
+
+	var id string
+	withID := RequestID(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = w.Header().Get(RequestIDHeader)
+	})))
+	if w = serve(withID, "GET", "/"); id == "" || id != w.Header().Get(RequestIDHeader) {
+		t.Errorf("Expected the handler to see the request ID header, got %q", id)
+	}
+
+	panicky := Recover(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	if w = serve(panicky, "GET", "/"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the panic to reach Recover, got %d", w.Code)
+	}
+
+	logged := make(chan string, 1)
+	defaultLogger := logger.Logger
+	logger.Logger = channelLogger{ILogger: defaultLogger, logged: logged}
+	defer func() {
+		logger.Logger = defaultLogger
+	}()
+	late := Recover(Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		panic("late boom")
+	})))
+	serve(late, "GET", "/")
+	select {
+	case msg := <-logged:
+		if !strings.Contains(msg, "late boom") {
+			t.Errorf("Expected the late panic to be logged, got %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the panic after the timeout to be logged")
+	}
+}
+
+// channelLogger sends the messages logged with Errorf to logged.
+type channelLogger struct {
+	logger.ILogger
+	logged chan string
+}
+
+func (l channelLogger) Errorf(format string, args ...any) {
+	l.logged <- fmt.Sprintf(format, args...)
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})(text("ok"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", "PUT")
+	r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected a 204 preflight response, got %d %q", w.Code, w.Body.String())
+	}
+	for header, expected := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := w.Header().Get(header); got != expected {
+			t.Errorf("Expected %s: %s, got %q", header, expected, got)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	handler.ServeHTTP(w, r)
+	if w.Body.String() != "ok" || w.Header().Get("Access-Control-Expose-Headers") != RequestIDHeader {
+		t.Errorf("Expected the request to be served with CORS headers, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for a disallowed origin")
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	CORS(CORSOptions{AllowedOrigins: []string{"*"}})(text("ok")).ServeHTTP(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected a wildcard origin, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for credentials from any origin")
+		}
+	}()
+	CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestRealIP(t *testing.T) {
+	var seen string
+	handler := RealIP("10.0.0.0/8", "192.168.1.1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+	}))
+
+	tests := []struct {
+		remote    string
+		forwarded string
+		realIP    string
+		expected  string
+	}{
+		{"10.0.0.1:1234", "203.0.113.7", "", "203.0.113.7"},
+		{"10.0.0.1:1234", "198.51.100.1, 203.0.113.7, 10.0.0.2", "", "203.0.113.7"},
+		{"192.168.1.1:1234", "", "203.0.113.9", "203.0.113.9"},
+		{"10.0.0.1:1234", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"203.0.113.1:1234", "198.51.100.1", "", "203.0.113.1:1234"},
+		{"10.0.0.1:1234", "not-an-ip", "", "10.0.0.1:1234"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-Ip", tt.realIP)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if seen != tt.expected {
+			t.Errorf("%s via %q: expected %s, got %s", tt.remote, tt.forwarded, tt.expected, seen)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected an invalid proxy to panic")
+		}
+	}()
+	RealIP("not-a-cidr")
+}
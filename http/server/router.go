@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Middleware wraps a handler with behaviour shared by several routes.
+type Middleware func(http.Handler) http.Handler
+
+// Router matches requests to handlers by method and path.
+// A pattern is an optional method and a path, e.g. "GET /users/{id}" or "/static/*".
+// A {name} segment matches any single segment and is read with Param, a trailing * matches the rest of the path
+// and is read with Param(r, "*"). Static segments win over parameters, which win over wildcards.
+// A pattern without a method matches every method, HEAD requests fall back to GET routes.
+// Trailing slashes are ignored. A path that matches no pattern gets a 404, a path that matches with another
+// method gets a 405 with an Allow header.
+type Router struct {
+	root             node
+	NotFound         http.Handler
+	MethodNotAllowed http.Handler
+}
+
+// Group registers routes under a shared path prefix and middleware.
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// node is a path segment in the routing tree.
+type node struct {
+	static    map[string]*node
+	param     *node
+	paramName string
+	wildcard  *node
+	handlers  map[string]http.Handler // by method, "" matches any method
+	pattern   string                  // path of the routes of n
+}
+
+type param struct {
+	name  string
+	value string
+}
+
+type paramsKey struct{}
+
+// routeKey is the context key of the *atomic.Pointer[string] the router sets to the pattern that matched.
+type routeKey struct{}
+
+// NewRouter creates an empty Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Param returns the value of the path parameter name of the route matching r, or "" if there is none.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).([]param)
+	for _, p := range params {
+		if p.name == name {
+			return p.value
+		}
+	}
+	return ""
+}
+
+// Handle registers handler for pattern wrapped by middlewares, it panics if the pattern is invalid or already registered.
+func (rt *Router) Handle(pattern string, handler http.Handler, middlewares ...Middleware) {
+	rt.Group("").Handle(pattern, handler, middlewares...)
+}
+
+// HandleFunc registers handler for pattern wrapped by middlewares.
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc, middlewares ...Middleware) {
+	rt.Handle(pattern, handler, middlewares...)
+}
+
+// Group returns a Group of routes under prefix wrapped by middlewares, the first middleware is the outermost.
+func (rt *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{router: rt, prefix: strings.TrimSuffix(prefix, "/"), middlewares: middlewares}
+}
+
+// Group returns a nested Group, its prefix and middlewares are appended to the ones of g.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		router:      g.router,
+		prefix:      g.prefix + strings.TrimSuffix(prefix, "/"),
+		middlewares: append(append([]Middleware(nil), g.middlewares...), middlewares...),
+	}
+}
+
+// Use appends middlewares to g, they apply to the routes registered afterwards.
+func (g *Group) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// Handle registers handler for pattern under the prefix and middlewares of g,
+// the route middlewares run after the ones of the group.
+func (g *Group) Handle(pattern string, handler http.Handler, middlewares ...Middleware) {
+	method, path := splitPattern(pattern)
+	if !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("server: pattern %q must start with /", pattern))
+	}
+	handler = chain(handler, middlewares...)
+	g.router.add(method, g.prefix+path, chain(handler, g.middlewares...))
+}
+
+// HandleFunc registers handler for pattern under the prefix and middlewares of g.
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc, middlewares ...Middleware) {
+	g.Handle(pattern, handler, middlewares...)
+}
+
+// chain wraps handler with middlewares, the first middleware is the outermost.
+func chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// splitPattern splits "GET /path" into its method and path, the method is empty if there is none.
+func splitPattern(pattern string) (string, string) {
+	if method, path, found := strings.Cut(strings.TrimSpace(pattern), " "); found {
+		return strings.ToUpper(method), strings.TrimSpace(path)
+	}
+	return "", strings.TrimSpace(pattern)
+}
+
+func segments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func (rt *Router) add(method, path string, handler http.Handler) {
+	n := &rt.root
+	segs := segments(path)
+	for i, seg := range segs {
+		switch {
+		case seg == "*":
+			if i != len(segs)-1 {
+				panic(fmt.Sprintf("server: wildcard must be the last segment of %q", path))
+			}
+			if n.wildcard == nil {
+				n.wildcard = &node{}
+			}
+			n = n.wildcard
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			name := seg[1 : len(seg)-1]
+			if n.param == nil {
+				n.param = &node{paramName: name}
+			} else if n.param.paramName != name {
+				panic(fmt.Sprintf("server: parameter {%s} of %q conflicts with {%s}", name, path, n.param.paramName))
+			}
+			n = n.param
+		default:
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+			child, found := n.static[seg]
+			if !found {
+				child = &node{}
+				n.static[seg] = child
+			}
+			n = child
+		}
+	}
+	if n.handlers == nil {
+		n.handlers = make(map[string]http.Handler)
+	}
+	if _, found := n.handlers[method]; found {
+		panic(fmt.Sprintf("server: multiple registrations for %s %s", method, path))
+	}
+	n.handlers[method] = handler
+	n.pattern = "/" + strings.Join(segs, "/")
+}
+
+// match finds the node of the route matching segs with a handler for method, and the path parameters.
+// If only routes with other methods match, it returns the first of them with ok false.
+func (n *node) match(segs []string, method string, params []param) (m *node, p []param, ok bool) {
+	if len(segs) == 0 {
+		if n.handlers == nil {
+			if n.wildcard != nil && n.wildcard.handlers != nil {
+				// A trailing wildcard also matches an empty rest
+				return n.wildcard, append(params, param{name: "*"}), n.wildcard.handler(method) != nil
+			}
+			return nil, nil, false
+		}
+		return n, params, n.handler(method) != nil
+	}
+	var fallback *node
+	var fallbackParams []param
+	try := func(child *node, params []param) bool {
+		m, p, ok := child.match(segs[1:], method, params)
+		if m != nil && !ok && fallback == nil {
+			fallback, fallbackParams = m, p
+		}
+		if ok {
+			fallback, fallbackParams = m, p
+		}
+		return ok
+	}
+	if child, found := n.static[segs[0]]; found && try(child, params) {
+		return fallback, fallbackParams, true
+	}
+	if n.param != nil {
+		// Copy so a failed branch does not share its backing array with the next one
+		p := append(append([]param(nil), params...), param{name: n.param.paramName, value: segs[0]})
+		if try(n.param, p) {
+			return fallback, fallbackParams, true
+		}
+	}
+	if n.wildcard != nil && n.wildcard.handlers != nil {
+		p := append(params, param{name: "*", value: strings.Join(segs, "/")})
+		if n.wildcard.handler(method) != nil {
+			return n.wildcard, p, true
+		}
+		if fallback == nil {
+			fallback, fallbackParams = n.wildcard, p
+		}
+	}
+	return fallback, fallbackParams, false
+}
+
+// handler returns the handler of n for method.
+func (n *node) handler(method string) http.Handler {
+	if h, found := n.handlers[method]; found {
+		return h
+	}
+	if method == http.MethodHead {
+		if h, found := n.handlers[http.MethodGet]; found {
+			return h
+		}
+	}
+	return n.handlers[""]
+}
+
+// allowed returns the methods n has a handler for.
+func (n *node) allowed() string {
+	methods := make([]string, 0, len(n.handlers))
+	for method := range n.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n, params, ok := rt.root.match(segments(r.URL.Path), r.Method, nil)
+	if n == nil {
+		if rt.NotFound != nil {
+			rt.NotFound.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if route, found := r.Context().Value(routeKey{}).(*atomic.Pointer[string]); found {
+		route.Store(&n.pattern)
+	}
+	if !ok {
+		w.Header().Set("Allow", n.allowed())
+		if rt.MethodNotAllowed != nil {
+			rt.MethodNotAllowed.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if len(params) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+	}
+	n.handler(r.Method).ServeHTTP(w, r)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func text(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(s))
+	}
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc("GET /users", text("list"))
+	router.HandleFunc("POST /users", text("create"))
+	router.HandleFunc("GET /users/me", text("me"))
+	router.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + Param(r, "id")))
+	})
+	router.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("delete " + Param(r, "id")))
+	})
+	router.HandleFunc("GET /users/{id}/posts/{post}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "id") + "/" + Param(r, "post")))
+	})
+	router.HandleFunc("/static/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file " + Param(r, "*")))
+	})
+	router.HandleFunc("/", text("home"))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/users", 200, "list"},
+		{"GET", "/users/", 200, "list"},
+		{"POST", "/users", 200, "create"},
+		{"HEAD", "/users", 200, "list"},
+		{"GET", "/users/me", 200, "me"},
+		{"GET", "/users/42", 200, "user 42"},
+		{"DELETE", "/users/me", 200, "delete me"},
+		{"GET", "/users/42/posts/7", 200, "42/7"},
+		{"PUT", "/static/css/site.css", 200, "file css/site.css"},
+		{"GET", "/static", 200, "file "},
+		{"GET", "/", 200, "home"},
+		{"PUT", "/users", 405, ""},
+		{"GET", "/missing", 404, ""},
+		{"GET", "/users/42/comments", 404, ""},
+	}
+	for _, tt := range tests {
+		w := serve(router, tt.method, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+		if tt.status == 200 && tt.method != "HEAD" && w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	if allow := serve(router, "PUT", "/users").Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Expected Allow: GET, POST, got %q", allow)
+	}
+}
+
+func TestRouterGroups(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	router := NewRouter()
+	api := router.Group("/api", tag("api"))
+	v1 := api.Group("/v1/", tag("v1"))
+	v1.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("item " + Param(r, "id")))
+	})
+	api.Use(tag("late"))
+	api.HandleFunc("GET /health", text("ok"))
+
+	if w := serve(router, "GET", "/api/v1/items/3"); w.Body.String() != "item 3" {
+		t.Errorf("Expected item 3, got %q", w.Body.String())
+	}
+	if strings.Join(calls, ",") != "api,v1" {
+		t.Errorf("Expected the group middlewares outermost first, got %v", calls)
+	}
+	calls = nil
+	serve(router, "GET", "/api/health")
+	if strings.Join(calls, ",") != "api,late" {
+		t.Errorf("Expected Use to apply to later routes, got %v", calls)
+	}
+	calls = nil
+	serve(router, "GET", "/api/missing")
+	if len(calls) != 0 {
+		t.Errorf("Expected no middleware on a 404, got %v", calls)
+	}
+}
+
+func TestRouterCustomErrors(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc("GET /", text("home"))
+	router.NotFound = text("custom 404")
+	router.MethodNotAllowed = text("custom 405")
+
+	if w := serve(router, "GET", "/missing"); w.Body.String() != "custom 404" {
+		t.Errorf("Expected the custom 404 handler, got %q", w.Body.String())
+	}
+	if w := serve(router, "POST", "/"); w.Body.String() != "custom 405" || w.Header().Get("Allow") != "GET" {
+		t.Errorf("Expected the custom 405 handler, got %q", w.Body.String())
+	}
+}
+
+func TestRouterPanicsOnConflicts(t *testing.T) {
+	tests := map[string][2]string{
+		"duplicate":  {"GET /a", "GET /a/"},
+		"param name": {"/users/{id}", "/users/{name}/posts"},
+		"wildcard":   {"/a", "/*/b"},
+		"relative":   {"/a", "GET a"},
+	}
+	for name, patterns := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			router := NewRouter()
+			router.HandleFunc(patterns[0], text(""))
+			router.HandleFunc(patterns[1], text(""))
+		}()
+	}
+}
+
+func TestServersHaveOwnRouters(t *testing.T) {
+	a, b := New(), New()
+	a.Router().HandleFunc("/", text("a"))
+	b.Router().HandleFunc("/", text("b"))
+	if w := serve(b.Router(), "GET", "/"); w.Body.String() != "b" {
+		t.Errorf("Expected each server to have its own routes, got %q", w.Body.String())
+	}
+}
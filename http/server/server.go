@@ -1,25 +1,239 @@
 package server
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
 
 type HttpServer interface {
 	Serve(host, port string, routes map[string]http.HandlerFunc) error
+	ServeContext(ctx context.Context, host, port string, routes map[string]http.HandlerFunc) error
+	Shutdown(ctx context.Context) error
+	Ready() bool
+	OnStart(fn func(addr net.Addr))
+	OnShutdown(fn func(ctx context.Context))
+	Router() *Router
+	Use(middlewares ...Middleware)
+	Health() *Health
+}
+
+// Options configures a Server.
+// DrainTimeout bounds how long Shutdown waits for in-flight requests when its context has no deadline,
+// zero means 30 seconds. Connections still open afterwards are closed.
+// ReadinessDelay is how long Shutdown keeps serving after Ready turns false, so load balancers polling
+// readiness stop sending traffic before the listener closes.
+// HandleSignals makes ServeContext shut down gracefully on SIGINT and SIGTERM.
+// Health configures the checks of Health.
+type Options struct {
+	DrainTimeout   time.Duration
+	ReadinessDelay time.Duration
+	HandleSignals  bool
+	Health         HealthOptions
 }
 
 type Server struct {
 	http.Server
+	router         *Router
+	drainTimeout   time.Duration
+	readinessDelay time.Duration
+	handleSignals  bool
+	ready          atomic.Bool
+	mu             sync.Mutex
+	onStart        []func(addr net.Addr)
+	onShutdown     []func(ctx context.Context)
+	middlewares    []Middleware
+	health         *Health
+	healthOptions  HealthOptions
+	started        bool
+}
+
+var defaultDrainTimeout = 30 * time.Second
+
+func New(opts ...Options) HttpServer {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	s := &Server{
+		Server:         http.Server{},
+		router:         NewRouter(),
+		drainTimeout:   opt.DrainTimeout,
+		readinessDelay: opt.ReadinessDelay,
+		handleSignals:  opt.HandleSignals,
+		healthOptions:  opt.Health,
+	}
+	if s.drainTimeout <= 0 {
+		s.drainTimeout = defaultDrainTimeout
+	}
+	return s
+}
+
+// Router returns the router of the server, routes can be registered on it before calling Serve.
+func (s *Server) Router() *Router {
+	if s.router == nil {
+		s.router = NewRouter()
+	}
+	return s.router
 }
 
-func New() HttpServer {
-	return &Server{
-		Server: http.Server{},
+// Health returns the health checks of the server. Calling it before Serve makes the server answer GET /healthz
+// with the liveness checks and GET /readyz with the readiness checks, which also fail while Ready is false.
+// The router is not safe for concurrent registration, so the first call after the server started panics.
+// Checks can be added to the returned Health at any time.
+func (s *Server) Health() *Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.health == nil {
+		if s.started {
+			panic("server: Health must be called before the server starts")
+		}
+		s.health = NewHealth(s.healthOptions)
+		s.health.ready = s.Ready
 	}
+	return s.health
 }
 
+// Use appends middlewares wrapping every request of the server, including the ones no route matches.
+// The first middleware is the outermost, they must be added before the server starts.
+func (s *Server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// Serve registers routes on the router of the server and listens on host:port until the server is shut down.
+// A route key is a Router pattern such as "/path" or "GET /users/{id}". Like with http.ServeMux, a key ending
+// in a slash such as "/" or "/static/" matches its whole subtree, and a key ending in "/{$}" only matches its
+// path. Host patterns are not supported.
 func (s *Server) Serve(host, port string, routes map[string]http.HandlerFunc) error {
-	s.Addr = host + ":" + port
+	return s.ServeContext(context.Background(), host, port, routes)
+}
+
+// ServeContext is Serve until ctx is done, then it shuts the server down gracefully.
+// It returns nil after a graceful shutdown, port "0" picks a free port which is passed to the OnStart hooks.
+func (s *Server) ServeContext(ctx context.Context, host, port string, routes map[string]http.HandlerFunc) error {
+	if s.handleSignals {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+	s.Addr = net.JoinHostPort(host, port)
 	for route, handler := range routes {
-		http.HandleFunc(route, handler)
+		s.Router().HandleFunc(servePattern(route), handler)
+	}
+	s.mu.Lock()
+	if s.health != nil && !s.started {
+		s.Router().Handle("GET /healthz", s.health.LiveHandler())
+		s.Router().Handle("GET /readyz", s.health.ReadyHandler())
+	}
+	s.started = true
+	s.mu.Unlock()
+	if s.Handler == nil {
+		s.Handler = chain(s.Router(), s.middlewares...)
+	}
+
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	s.ready.Store(true)
+	s.mu.Lock()
+	onStart := slices.Clone(s.onStart)
+	s.mu.Unlock()
+	for _, fn := range onStart {
+		fn(listener.Addr())
 	}
-	return s.ListenAndServe()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Server.Serve(listener)
+	}()
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = s.Shutdown(context.WithoutCancel(ctx))
+		<-errCh
+	}
+	s.ready.Store(false)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// servePattern turns a http.ServeMux style key of the Serve routes into a Router pattern.
+func servePattern(route string) string {
+	method, path := splitPattern(route)
+	switch {
+	case strings.HasSuffix(path, "/{$}"):
+		path = strings.TrimSuffix(path, "{$}")
+	case strings.HasSuffix(path, "/"):
+		path += "*"
+	}
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
+
+// Shutdown stops the server gracefully: Ready turns false, the server keeps serving for the ReadinessDelay,
+// then stops accepting connections and waits for in-flight requests until ctx is done or the DrainTimeout
+// passes. Connections left are closed. The OnShutdown hooks run last.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+	if s.readinessDelay > 0 {
+		timer := time.NewTimer(s.readinessDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	drainCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(ctx, s.drainTimeout)
+		defer cancel()
+	}
+	err := s.Server.Shutdown(drainCtx)
+	if err != nil {
+		s.Server.Close()
+	}
+
+	s.mu.Lock()
+	onShutdown := slices.Clone(s.onShutdown)
+	s.mu.Unlock()
+	for _, fn := range onShutdown {
+		fn(ctx)
+	}
+	return err
+}
+
+// Ready reports whether the server is serving and not shutting down.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// OnStart registers fn to be called with the listening address once the server accepts connections.
+func (s *Server) OnStart(fn func(addr net.Addr)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onStart = append(s.onStart, fn)
+}
+
+// OnShutdown registers fn to be called by Shutdown after the in-flight requests are drained,
+// to release the resources they used such as database connections.
+func (s *Server) OnShutdown(fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, fn)
 }
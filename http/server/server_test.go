@@ -2,7 +2,11 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -27,16 +31,31 @@ func TestServe(t *testing.T) {
 			ResponseOK(w, data, "")
 		},
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	addrCh := make(chan net.Addr, 1)
+	server.OnStart(func(addr net.Addr) {
+		addrCh <- addr
+	})
+	errCh := make(chan error, 1)
 	go func() {
-		if err := server.Serve("localhost", "8080", routes); err != nil {
-			t.Fatalf("Could not start server: %v", err)
+		errCh <- server.ServeContext(ctx, "localhost", "0", routes)
+	}()
+	var base string
+	select {
+	case addr := <-addrCh:
+		base = "http://" + addr.String()
+	case err := <-errCh:
+		t.Fatalf("Could not start server: %v", err)
+	}
+	defer func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Errorf("Expected a graceful shutdown, got %v", err)
 		}
 	}()
 
-	time.Sleep(10 * time.Second)
-
 	// Test GET request
-	resp, err := http.Get("http://localhost:8080/get")
+	resp, err := http.Get(base + "/get")
 	if err != nil {
 		t.Fatalf("Could not send GET request: %v", err)
 	}
@@ -57,7 +76,7 @@ func TestServe(t *testing.T) {
 	// Test POST request
 	testData := TestData{Message: "Hello, POST!"}
 	jsonData, _ := json.Marshal(testData)
-	resp, err = http.Post("http://localhost:8080/post", "application/json", bytes.NewBuffer(jsonData))
+	resp, err = http.Post(base+"/post", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		t.Fatalf("Could not send POST request: %v", err)
 	}
@@ -79,3 +98,150 @@ func TestServe(t *testing.T) {
 		t.Errorf("Expected '%v'; got '%v'", testData.Message, responseData["message"])
 	}
 }
+
+// startServer serves s on a free port until the test ends and returns its base URL.
+func startServer(t *testing.T, s HttpServer, routes map[string]http.HandlerFunc) string {
+	t.Helper()
+	addrCh := make(chan net.Addr, 1)
+	s.OnStart(func(addr net.Addr) {
+		addrCh <- addr
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ServeContext(context.Background(), "127.0.0.1", "0", routes)
+	}()
+	select {
+	case addr := <-addrCh:
+		t.Cleanup(func() {
+			s.Shutdown(context.Background())
+			<-errCh
+		})
+		return "http://" + addr.String()
+	case err := <-errCh:
+		t.Fatalf("Could not start server: %v", err)
+		return ""
+	}
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	s := New(Options{ReadinessDelay: 20 * time.Millisecond})
+	started := make(chan struct{})
+	base := startServer(t, s, map[string]http.HandlerFunc{
+		"/slow": func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			ResponseOK(w, "done", "")
+		},
+	})
+	var hooks []string
+	s.OnShutdown(func(ctx context.Context) {
+		hooks = append(hooks, "shutdown")
+	})
+	if !s.Ready() {
+		t.Error("Expected the server to be ready once started")
+	}
+
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			respCh <- nil
+			return
+		}
+		respCh <- resp
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- s.Shutdown(context.Background())
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if s.Ready() {
+		t.Error("Expected readiness to flip as soon as shutdown starts")
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Expected the in-flight request to drain, got %v", err)
+	}
+	resp := <-respCh
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the in-flight request to complete, got %v", resp)
+	}
+	resp.Body.Close()
+	if len(hooks) != 1 {
+		t.Errorf("Expected the OnShutdown hook to run once, got %v", hooks)
+	}
+	if _, err := http.Get(base + "/slow"); err == nil {
+		t.Error("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	s := New(Options{DrainTimeout: 20 * time.Millisecond})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	base := startServer(t, s, map[string]http.HandlerFunc{
+		"/stuck": func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		},
+	})
+	go http.Get(base + "/stuck")
+	<-started
+
+	start := time.Now()
+	if err := s.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the drain to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Shutdown to give up after the drain timeout, took %v", elapsed)
+	}
+}
+
+func TestServeContextListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	if err := New().Serve("127.0.0.1", port, nil); err == nil {
+		t.Error("Expected an error for a port in use")
+	}
+}
+
+func TestServeMuxPatterns(t *testing.T) {
+	route := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}
+	}
+	base := startServer(t, New(), map[string]http.HandlerFunc{
+		"/":         route("catch-all"),
+		"/static/":  route("static"),
+		"/api/{$}":  route("api"),
+		"GET /ping": route("ping"),
+	})
+
+	tests := map[string]string{
+		"/":             "catch-all",
+		"/anything":     "catch-all",
+		"/a/b":          "catch-all",
+		"/static/a.css": "static",
+		"/api":          "api",
+		"/api/users":    "catch-all",
+		"/ping":         "ping",
+	}
+	for path, expected := range tests {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("Could not send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != expected {
+			t.Errorf("%s: expected %s, got %d %s", path, expected, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Counter is a value that only goes up, such as a number of requests, for every set of label values.
+type Counter struct {
+	vec[atomicFloat]
+}
+
+// Gauge is a value that goes up and down, such as a number of connections, for every set of label values.
+type Gauge struct {
+	vec[atomicFloat]
+}
+
+// funcMetric is a counter or gauge without labels whose value is read from a function when it is exposed.
+type funcMetric struct {
+	kind string
+	fn   func() float64
+}
+
+// NewCounter registers a counter with labelNames on r. If a counter with the same name and labels is already
+// registered it is returned, it panics if name is used by another metric.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{}
+	c.init(labelNames, func() *atomicFloat { return &atomicFloat{} })
+	return register(r, name, help, c, func(existing *Counter) bool {
+		return slices.Equal(existing.labels, c.labels)
+	})
+}
+
+// NewGauge registers a gauge with labelNames on r. If a gauge with the same name and labels is already
+// registered it is returned, it panics if name is used by another metric.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{}
+	g.init(labelNames, func() *atomicFloat { return &atomicFloat{} })
+	return register(r, name, help, g, func(existing *Gauge) bool {
+		return slices.Equal(existing.labels, g.labels)
+	})
+}
+
+// NewCounterFunc registers a counter whose value is returned by fn, such as the hits of a cache.
+// fn must be safe for concurrent use and never decrease. It panics if name is already registered.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	register(r, name, help, &funcMetric{kind: "counter", fn: fn}, nil)
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by fn, such as the size of a pool.
+// fn must be safe for concurrent use. It panics if name is already registered.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	register(r, name, help, &funcMetric{kind: "gauge", fn: fn}, nil)
+}
+
+// NewCounter registers a counter on the Default registry.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return Default.NewCounter(name, help, labelNames...)
+}
+
+// NewGauge registers a gauge on the Default registry.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	return Default.NewGauge(name, help, labelNames...)
+}
+
+// NewCounterFunc registers a counter function on the Default registry.
+func NewCounterFunc(name, help string, fn func() float64) {
+	Default.NewCounterFunc(name, help, fn)
+}
+
+// NewGaugeFunc registers a gauge function on the Default registry.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	Default.NewGaugeFunc(name, help, fn)
+}
+
+// Inc adds one to the counter of labelValues, which are given in the order of the label names.
+// Like every method taking label values, it panics if their number does not match the label names.
+func (c *Counter) Inc(labelValues ...string) {
+	c.get(labelValues).add(1)
+}
+
+// Add adds delta to the counter of labelValues, it panics if delta is negative.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.get(labelValues).add(delta)
+}
+
+// Value returns the counter of labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.get(labelValues).load()
+}
+
+func (c *Counter) typeName() string {
+	return "counter"
+}
+
+func (c *Counter) write(buf *bytes.Buffer, name string) {
+	for _, s := range c.snapshot() {
+		writeSample(buf, name, c.labels, s.labelValues, "", "", s.value.load())
+	}
+}
+
+// Set sets the gauge of labelValues to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.get(labelValues).store(v)
+}
+
+// Add adds delta, which may be negative, to the gauge of labelValues.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.get(labelValues).add(delta)
+}
+
+// Inc adds one to the gauge of labelValues.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.get(labelValues).add(1)
+}
+
+// Dec subtracts one from the gauge of labelValues.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.get(labelValues).add(-1)
+}
+
+// Value returns the gauge of labelValues.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.get(labelValues).load()
+}
+
+func (g *Gauge) typeName() string {
+	return "gauge"
+}
+
+func (g *Gauge) write(buf *bytes.Buffer, name string) {
+	for _, s := range g.snapshot() {
+		writeSample(buf, name, g.labels, s.labelValues, "", "", s.value.load())
+	}
+}
+
+func (f *funcMetric) typeName() string {
+	return f.kind
+}
+
+func (f *funcMetric) labelNames() []string {
+	return nil
+}
+
+func (f *funcMetric) write(buf *bytes.Buffer, name string) {
+	writeSample(buf, name, nil, nil, "", "", f.fn())
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets, in seconds they suit request latencies.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations, such as request durations, in buckets for every set of label values.
+type Histogram struct {
+	vec[histogramSeries]
+	buckets []float64
+}
+
+// histogramSeries holds the observations of a histogram for a set of label values.
+type histogramSeries struct {
+	counts []atomic.Uint64 // by bucket, not cumulative
+	count  atomic.Uint64
+	sum    atomicFloat
+}
+
+// NewHistogram registers a histogram with the upper bounds buckets and labelNames on r, nil buckets means
+// DefBuckets. A +Inf bucket is always added. If a histogram with the same name, buckets and labels is already
+// registered it is returned. It panics if the buckets are not increasing or if name is used by another metric.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic(fmt.Sprintf("metrics: buckets of %s must be increasing", name))
+		}
+	}
+	if len(buckets) > 0 && math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+	if slices.Contains(labelNames, "le") {
+		panic(fmt.Sprintf("metrics: le is reserved for the buckets of %s", name))
+	}
+
+	h := &Histogram{buckets: slices.Clone(buckets)}
+	h.init(labelNames, func() *histogramSeries {
+		return &histogramSeries{counts: make([]atomic.Uint64, len(h.buckets))}
+	})
+	return register(r, name, help, h, func(existing *Histogram) bool {
+		return slices.Equal(existing.labels, h.labels) && slices.Equal(existing.buckets, h.buckets)
+	})
+}
+
+// NewHistogram registers a histogram on the Default registry.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labelNames...)
+}
+
+// LinearBuckets returns count buckets from start, spaced width apart.
+func LinearBuckets(start, width float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start + float64(i)*width
+	}
+	return buckets
+}
+
+// ExponentialBuckets returns count buckets from start, each factor times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start * math.Pow(factor, float64(i))
+	}
+	return buckets
+}
+
+// Observe adds v to the histogram of labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.get(labelValues)
+	// Counted before its bucket, so a concurrent write never exposes a +Inf bucket below the others
+	s.count.Add(1)
+	// The first bucket whose upper bound is at least v, none means only the +Inf bucket
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i].Add(1)
+	}
+	s.sum.add(v)
+}
+
+func (h *Histogram) typeName() string {
+	return "histogram"
+}
+
+func (h *Histogram) write(buf *bytes.Buffer, name string) {
+	for _, s := range h.snapshot() {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.value.counts[i].Load()
+			writeSample(buf, name+"_bucket", h.labels, s.labelValues, "le", formatFloat(upper), float64(cumulative))
+		}
+		count := s.value.count.Load()
+		writeSample(buf, name+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(count))
+		writeSample(buf, name+"_sum", h.labels, s.labelValues, "", "", s.value.sum.load())
+		writeSample(buf, name+"_count", h.labels, s.labelValues, "", "", float64(count))
+	}
+}
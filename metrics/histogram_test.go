@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 0.5, 1}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		h.Observe(v, "/users")
+	}
+
+	expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/users",le="0.1"} 2
+latency_seconds_bucket{route="/users",le="0.5"} 3
+latency_seconds_bucket{route="/users",le="1"} 3
+latency_seconds_bucket{route="/users",le="+Inf"} 4
+latency_seconds_sum{route="/users"} 2.45
+latency_seconds_count{route="/users"} 4
+`
+	if got := expose(t, r); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	if r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 0.5, 1, math.Inf(1)}, "route") != h {
+		t.Errorf("Expected the same histogram with an explicit +Inf bucket")
+	}
+	for name, fn := range map[string]func(){
+		"other buckets": func() { r.NewHistogram("latency_seconds", "", nil, "route") },
+		"unsorted":      func() { r.NewHistogram("sizes", "", []float64{2, 1}) },
+		"le label":      func() { r.NewHistogram("sizes", "", nil, "le") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	if b := LinearBuckets(1, 2, 3); !slices.Equal(b, []float64{1, 3, 5}) {
+		t.Errorf("Expected [1 3 5], got %v", b)
+	}
+	if b := ExponentialBuckets(1, 10, 3); !slices.Equal(b, []float64{1, 10, 100}) {
+		t.Errorf("Expected [1 10 100], got %v", b)
+	}
+}
@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds metrics and exposes them in the Prometheus text format.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+// metric is a registered collector with its name and help.
+type metric struct {
+	name string
+	help string
+	c    collector
+}
+
+// collector is a counter, gauge or histogram with its series.
+type collector interface {
+	typeName() string
+	labelNames() []string
+	write(buf *bytes.Buffer, name string)
+}
+
+// Default is the registry used by the package functions.
+var Default = NewRegistry()
+
+var (
+	nameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// register adds c under name, or returns the collector already registered under name if it is compatible
+// according to same. It panics if name or a label name is invalid, or if an incompatible metric is registered.
+func register[C collector](r *Registry, name, help string, c C, same func(existing C) bool) C {
+	if !nameRe.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range c.labelNames() {
+		if !labelRe.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("metrics: invalid label name %q of %s", label, name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, found := r.metrics[name]; found {
+		if existing, ok := m.c.(C); ok && same != nil && same(existing) {
+			return existing
+		}
+		panic(fmt.Sprintf("metrics: %s is already registered as a %s with other labels or options", name, m.c.typeName()))
+	}
+	r.metrics[name] = &metric{name: name, help: help, c: c}
+	return c
+}
+
+// Unregister removes the metric name, it reports whether it was registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, found := r.metrics[name]
+	delete(r.metrics, name)
+	return found
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format, sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		if m.help != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+		}
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", m.name, m.c.typeName())
+		m.c.write(&buf, m.name)
+	}
+	return buf.WriteTo(w)
+}
+
+// Handler serves the metrics of r, to be registered as GET /metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// Handler serves the metrics of the Default registry.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// vec holds the series of a metric by label values.
+type vec[T any] struct {
+	labels   []string
+	newValue func() *T
+	mu       sync.RWMutex
+	series   map[string]*series[T]
+}
+
+// series is the value of a metric for a set of label values.
+type series[T any] struct {
+	labelValues []string
+	value       *T
+}
+
+func (v *vec[T]) init(labelNames []string, newValue func() *T) {
+	v.labels, v.newValue, v.series = slices.Clone(labelNames), newValue, make(map[string]*series[T])
+	if len(labelNames) == 0 {
+		// A metric without labels is exposed from the start
+		v.get(nil)
+	}
+}
+
+func (v *vec[T]) labelNames() []string {
+	return v.labels
+}
+
+// get returns the value for labelValues, creating it if needed. It panics if the number of values is wrong.
+func (v *vec[T]) get(labelValues []string) *T {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values for %v, got %d", len(v.labels), v.labels, len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mu.RLock()
+	s, found := v.series[key]
+	v.mu.RUnlock()
+	if found {
+		return s.value
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, found = v.series[key]; !found {
+		s = &series[T]{labelValues: slices.Clone(labelValues), value: v.newValue()}
+		v.series[key] = s
+	}
+	return s.value
+}
+
+// snapshot returns the series sorted by label values.
+func (v *vec[T]) snapshot() []*series[T] {
+	v.mu.RLock()
+	all := make([]*series[T], 0, len(v.series))
+	for _, s := range v.series {
+		all = append(all, s)
+	}
+	v.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool { return slices.Compare(all[i].labelValues, all[j].labelValues) < 0 })
+	return all
+}
+
+// atomicFloat is a float64 updated atomically.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// writeSample writes a sample line, extraLabel and extraValue are appended to the labels if set.
+func writeSample(buf *bytes.Buffer, name string, labelNames, labelValues []string, extraLabel, extraValue string, value float64) {
+	buf.WriteString(name)
+	if len(labelNames) > 0 || extraLabel != "" {
+		buf.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, label, labelValues[i])
+		}
+		if extraLabel != "" {
+			if len(labelNames) > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, extraLabel, extraValue)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
+
+func writeLabel(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	buf.WriteString(labelValueEscaper.Replace(value))
+	buf.WriteByte('"')
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
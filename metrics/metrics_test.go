@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func expose(t *testing.T, r *Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("Could not write metrics: %v", err)
+	}
+	return sb.String()
+}
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests.\nBy path.", "method", "path")
+	requests.Inc("GET", `/a"b\c`)
+	requests.Add(2, "GET", "/")
+	r.NewGauge("up", "")
+	r.NewGaugeFunc("pool_size", "Connections in the pool.", func() float64 { return 3.5 })
+
+	expected := `# HELP pool_size Connections in the pool.
+# TYPE pool_size gauge
+pool_size 3.5
+# HELP requests_total Requests.\nBy path.
+# TYPE requests_total counter
+requests_total{method="GET",path="/"} 2
+requests_total{method="GET",path="/a\"b\\c"} 1
+# TYPE up gauge
+up 0
+`
+	if got := expose(t, r); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") || w.Body.String() != expected {
+		t.Errorf("Expected the handler to serve the exposition, got %q", w.Body.String())
+	}
+
+	if !r.Unregister("up") || r.Unregister("up") || strings.Contains(expose(t, r), "up 0") {
+		t.Errorf("Expected up to be unregistered once")
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("hits_total", "Hits.", "cache")
+	if r.NewCounter("hits_total", "Hits.", "cache") != c {
+		t.Errorf("Expected registering the same counter twice to return it")
+	}
+
+	tests := map[string]func(){
+		"other type":    func() { r.NewGauge("hits_total", "Hits.", "cache") },
+		"other labels":  func() { r.NewCounter("hits_total", "Hits.", "shard") },
+		"func metric":   func() { r.NewCounterFunc("hits_total", "Hits.", func() float64 { return 0 }) },
+		"invalid name":  func() { r.NewCounter("hits-total", "") },
+		"invalid label": func() { r.NewCounter("misses_total", "", "__name") },
+		"label count":   func() { c.Inc() },
+		"decrease":      func() { c.Add(-1, "a") },
+	}
+	for name, fn := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("ops_total", "", "worker")
+	g := r.NewGauge("in_flight", "")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc(worker)
+				c.Inc("all")
+				g.Inc()
+				g.Dec()
+			}
+			expose(t, r)
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+	if c.Value("all") != 8000 || c.Value("c") != 1000 || g.Value() != 0 {
+		t.Errorf("Expected 8000, 1000 and 0, got %v, %v and %v", c.Value("all"), c.Value("c"), g.Value())
+	}
+}